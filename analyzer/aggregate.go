@@ -0,0 +1,329 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AggregateOutput groups results by the values of some Analyzers, and outputs a row per group with aggregations of
+// other Analyzers onto another Output, e.g. "win rate and average APM per matchup per map" is:
+//
+//	-group-by my-matchup,map-name -aggregate count,avg(my-apm),rate(my-inferred-win)
+//
+// Rows are only output on Post, sorted by their group-by values. Note that ExecuteWithResults still returns the
+// results of each replay rather than aggregated ones.
+type AggregateOutput struct {
+	output           Output
+	groupBy          []string // display names of the Analyzers to group by
+	aggregations     []aggregation
+	analyzerRequests [][]string
+	groupByPos       []int
+	groups           map[string]*aggregateGroup
+}
+
+// aggregation is an aggregate function over the results of an Analyzer on a group, e.g. avg(my-apm).
+type aggregation struct {
+	function    string // count, avg, sum, min, max or rate
+	displayName string // of the aggregated Analyzer; empty for count
+	pos         int
+}
+
+type aggregateGroup struct {
+	keys         []Result
+	accumulators []accumulator
+}
+
+// accumulator accumulates the non-null results of an Analyzer on a group. Numbers are accumulated as float64;
+// typ is the ResultType of the accumulated results, to output sum/min/max with the same type.
+type accumulator struct {
+	rows, count, trueCount int
+	sum, min, max          float64
+	typ                    ResultType
+}
+
+var aggregateFunctions = map[string]string{
+	"count": "number of rows in the group",
+	"avg":   "average of a numeric analyzer",
+	"sum":   "sum of a numeric analyzer",
+	"min":   "minimum of a numeric analyzer",
+	"max":   "maximum of a numeric analyzer",
+	"rate":  "ratio of true results of a true/false analyzer",
+}
+
+// NewAggregateOutput is the AggregateOutput constructor. groupBy is a comma-separated list of Analyzers with
+// arguments in parentheses, e.g. "my-matchup,my-first-specific-unit-seconds(Spawning Pool)", and aggregations is a
+// comma-separated list of count, avg(analyzer), sum(analyzer), min(analyzer), max(analyzer) and rate(analyzer).
+// If there are no aggregations, groups are counted. The Analyzers involved must be requested to the Executor: see
+// AnalyzerRequests.
+func NewAggregateOutput(output Output, groupBy string, aggregations string) (*AggregateOutput, error) {
+	o := &AggregateOutput{output: output, groups: map[string]*aggregateGroup{}}
+	for _, spec := range splitAggregateSpecs(groupBy) {
+		analyzerRequest, err := parseAnalyzerSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -group-by: %v", err)
+		}
+		o.groupBy = append(o.groupBy, analyzerRequestDisplayName(analyzerRequest))
+		o.analyzerRequests = append(o.analyzerRequests, analyzerRequest)
+	}
+	if strings.TrimSpace(aggregations) == "" {
+		aggregations = "count"
+	}
+	for _, spec := range splitAggregateSpecs(aggregations) {
+		a, analyzerRequest, err := parseAggregation(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -aggregate: %v", err)
+		}
+		o.aggregations = append(o.aggregations, a)
+		if analyzerRequest != nil {
+			o.analyzerRequests = append(o.analyzerRequests, analyzerRequest)
+		}
+	}
+	return o, nil
+}
+
+// AnalyzerRequests returns the requests of the Analyzers to group by and to aggregate, which must be requested to
+// the Executor.
+func (o *AggregateOutput) AnalyzerRequests() [][]string {
+	analyzerRequests := make([][]string, len(o.analyzerRequests))
+	for i, analyzerRequest := range o.analyzerRequests {
+		analyzerRequests[i] = cloneStringSlice(analyzerRequest)
+	}
+	return analyzerRequests
+}
+
+// Pre runs at the beginning of the replay analyzing cycle.
+func (o *AggregateOutput) Pre(analyzerWrappers []analyzerWrapper) error {
+	o.groupByPos = make([]int, len(o.groupBy))
+	for i, displayName := range o.groupBy {
+		if o.groupByPos[i] = findAnalyzerWrapperPos(analyzerWrappers, displayName); o.groupByPos[i] < 0 {
+			return fmt.Errorf("analyzer %v to group by was not requested", displayName)
+		}
+	}
+	columns := []analyzerWrapper{}
+	for i, displayName := range o.groupBy {
+		columns = append(columns, analyzerWrapper{analyzer: analyzerWrappers[o.groupByPos[i]].analyzer, displayName: displayName})
+	}
+	for i, a := range o.aggregations {
+		if a.function != "count" {
+			if o.aggregations[i].pos = findAnalyzerWrapperPos(analyzerWrappers, a.displayName); o.aggregations[i].pos < 0 {
+				return fmt.Errorf("analyzer %v to aggregate was not requested", a.displayName)
+			}
+		}
+		columns = append(columns, analyzerWrapper{displayName: a.String()})
+	}
+	return o.output.Pre(columns)
+}
+
+// ReplayResults runs at each replay result cycle.
+func (o *AggregateOutput) ReplayResults(results []Result) error {
+	var (
+		keys    = make([]Result, len(o.groupByPos))
+		keyStrs = make([]string, len(o.groupByPos))
+	)
+	for i, pos := range o.groupByPos {
+		keys[i], keyStrs[i] = results[pos], results[pos].String()
+	}
+	key := strings.Join(keyStrs, "\x00")
+	group, ok := o.groups[key]
+	if !ok {
+		group = &aggregateGroup{keys: keys, accumulators: make([]accumulator, len(o.aggregations))}
+		o.groups[key] = group
+	}
+	for i, a := range o.aggregations {
+		group.accumulators[i].rows++
+		if a.function != "count" {
+			group.accumulators[i].add(results[a.pos])
+		}
+	}
+	return nil
+}
+
+// Post runs at the end of the replay analyzing cycle.
+func (o *AggregateOutput) Post() error {
+	keys := make([]string, 0, len(o.groups))
+	for key := range o.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		group := o.groups[key]
+		row := append([]Result{}, group.keys...)
+		for i, a := range o.aggregations {
+			row = append(row, group.accumulators[i].result(a.function))
+		}
+		if err := o.output.ReplayResults(row); err != nil {
+			return err
+		}
+	}
+	return o.output.Post()
+}
+
+func (a aggregation) String() string {
+	if a.function == "count" {
+		return a.function
+	}
+	return fmt.Sprintf("%v(%v)", a.function, a.displayName)
+}
+
+func (a *accumulator) add(result Result) {
+	var value float64
+	switch result.Type() {
+	case ResultTypeBool:
+		if result.IsTrue() {
+			a.trueCount++
+		}
+	case ResultTypeInt:
+		value = float64(result.Int())
+	case ResultTypeFloat:
+		value = result.Float()
+	case ResultTypeDuration:
+		value = result.Duration().Seconds()
+	default:
+		return // N.B. nulls are not accumulated
+	}
+	if a.count == 0 || value < a.min {
+		a.min = value
+	}
+	if a.count == 0 || value > a.max {
+		a.max = value
+	}
+	a.count++
+	a.sum += value
+	a.typ = result.Type()
+}
+
+func (a accumulator) result(function string) Result {
+	if function == "count" {
+		return NewIntResult(a.rows)
+	}
+	if a.count == 0 {
+		return NewNullResult()
+	}
+	switch function {
+	case "rate":
+		return NewFloatResult(roundAggregate(float64(a.trueCount) / float64(a.count)))
+	case "avg":
+		if a.typ == ResultTypeDuration {
+			return NewDurationResult(time.Duration(a.sum / float64(a.count) * float64(time.Second)))
+		}
+		return NewFloatResult(roundAggregate(a.sum / float64(a.count)))
+	case "sum":
+		return a.typed(a.sum)
+	case "min":
+		return a.typed(a.min)
+	}
+	return a.typed(a.max)
+}
+
+// typed returns the accumulated value with the ResultType of the accumulated results.
+func (a accumulator) typed(value float64) Result {
+	switch a.typ {
+	case ResultTypeInt:
+		return NewIntResult(int(value))
+	case ResultTypeDuration:
+		return NewDurationResult(time.Duration(value * float64(time.Second)))
+	}
+	return NewFloatResult(roundAggregate(value))
+}
+
+func roundAggregate(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// parseAggregation parses e.g. "count" or "avg(my-apm)", returning the request of the aggregated Analyzer, if any.
+func parseAggregation(spec string) (aggregation, []string, error) {
+	var (
+		function = spec
+		inner    string
+	)
+	if i := strings.Index(spec, "("); i >= 0 {
+		if !strings.HasSuffix(spec, ")") {
+			return aggregation{}, nil, fmt.Errorf("expected ')' at the end of %v", spec)
+		}
+		function, inner = strings.TrimSpace(spec[:i]), spec[i+1:len(spec)-1]
+	}
+	description, ok := aggregateFunctions[function]
+	if !ok {
+		return aggregation{}, nil, fmt.Errorf("unknown aggregate function %v in %v; valid functions are %v", function, spec, strings.Join(sortedAggregateFunctions(), ", "))
+	}
+	if function == "count" {
+		if inner != "" {
+			return aggregation{}, nil, fmt.Errorf("count doesn't take an analyzer, but got %v", spec)
+		}
+		return aggregation{function: function}, nil, nil
+	}
+	analyzerRequest, err := parseAnalyzerSpec(inner)
+	if err != nil {
+		return aggregation{}, nil, fmt.Errorf("%v in %v", err, spec)
+	}
+	if an, ok := Analyzers[analyzerRequest[0]]; ok {
+		if (function == "rate" && !an.IsBooleanResult()) || (function != "rate" && !an.IsNumericResult()) {
+			return aggregation{}, nil, fmt.Errorf("%v is the %v, but analyzer %v's results are of type %v", function, description, an.Name(), an.ResultType())
+		} // N.B. unknown analyzers are reported by the Executor
+	}
+	return aggregation{function: function, displayName: analyzerRequestDisplayName(analyzerRequest)}, analyzerRequest, nil
+}
+
+func sortedAggregateFunctions() []string {
+	functions := make([]string, 0, len(aggregateFunctions))
+	for function := range aggregateFunctions {
+		functions = append(functions, function)
+	}
+	sort.Strings(functions)
+	return functions
+}
+
+// parseAnalyzerSpec parses an Analyzer name with optional arguments in parentheses (as in -where atoms) into an
+// analyzer request, e.g. "my-first-specific-unit-seconds(Spawning Pool)".
+func parseAnalyzerSpec(spec string) ([]string, error) {
+	p := &whereParser{s: spec}
+	if _, err := p.parseAtom(); err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.i < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at position %v of %v", p.s[p.i:], p.i, spec)
+	}
+	return p.atoms[0].analyzerRequest, nil
+}
+
+// splitAggregateSpecs splits a comma-separated list of specs, ignoring commas within parentheses.
+func splitAggregateSpecs(s string) []string {
+	var (
+		specs = []string{}
+		depth = 0
+		start = 0
+	)
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] == '(' {
+			depth++
+		} else if i < len(s) && s[i] == ')' {
+			depth--
+		} else if i == len(s) || (s[i] == ',' && depth == 0) {
+			if spec := strings.TrimSpace(s[start:i]); spec != "" {
+				specs = append(specs, spec)
+			}
+			start = i + 1
+		}
+	}
+	return specs
+}
+
+// analyzerRequestDisplayName is the displayName of the analyzerWrapper for the given analyzer request.
+func analyzerRequestDisplayName(analyzerRequest []string) string {
+	if len(analyzerRequest) == 1 {
+		return analyzerRequest[0]
+	}
+	return fmt.Sprintf("%v(%v)", analyzerRequest[0], strings.Join(analyzerRequest[1:], ","))
+}
+
+func findAnalyzerWrapperPos(analyzerWrappers []analyzerWrapper, displayName string) int {
+	for _, w := range analyzerWrappers {
+		if w.displayName == displayName && !w.isHidden {
+			return w.pos
+		}
+	}
+	return -1
+}
@@ -2,7 +2,7 @@ package analyzer
 
 import (
 	"fmt"
-	"sort"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -20,13 +20,13 @@ type analyzerImpl struct {
 	version                 int
 	dependsOn               map[string]struct{}
 	isStringFlag            bool
-	isBooleanResult         bool
+	resultType              ResultType
 	requiresParsingCommands bool
 	requiresParsingMapData  bool
 	argumentValidator       argumentValidator
 	analyzerProcessor       analyzerProcessor
 
-	result string
+	result Result
 	done   bool
 }
 
@@ -39,11 +39,13 @@ func (a analyzerImpl) Name() string { return a.name }
 func (a analyzerImpl) Description() string { return a.description }
 
 // DependsOn are the Analyzer Name's whose Results this Analyzer depends on: for building DAG.
+// The Executor runs dependencies (without arguments) before this Analyzer, instantiating them if they
+// weren't requested, and hands their Results to StartReadingReplay.
 func (a analyzerImpl) DependsOn() map[string]struct{} { return a.dependsOn }
 
 // IsDone Returns true if the analyzer is finished calculating the result, and
 // returns it. Shouldn't be called before calling StartReadingReplay.
-func (a analyzerImpl) IsDone() (string, bool) { return a.result, a.done }
+func (a analyzerImpl) IsDone() (Result, bool) { return a.result, a.done }
 
 // Version is useful for managing updates to an Analyzer: whenever an update is made to an
 // analyzer, the Version should be numerically higher. Then, if there's a cached
@@ -56,9 +58,19 @@ func (a analyzerImpl) RequiresParsingCommands() bool { return a.requiresParsingC
 // RequiresParsingMapData is true if this Analyzer requires parsing map data from the replay
 func (a analyzerImpl) RequiresParsingMapData() bool { return a.requiresParsingMapData }
 
+// ResultType is the type of the Result of this Analyzer. Note that Results can also be null, e.g. when the -me
+// player is not in the replay.
+func (a analyzerImpl) ResultType() ResultType { return a.resultType }
+
 // IsBooleanResult Determines if the result type is "true"/"false". Used for providing -filter-- and -filter-not--
 // flags.
-func (a analyzerImpl) IsBooleanResult() bool { return a.isBooleanResult }
+func (a analyzerImpl) IsBooleanResult() bool { return a.resultType == ResultTypeBool }
+
+// IsNumericResult Determines if the result type is a number (int, float or duration). Used for providing -filter--
+// and -filter-not-- flags with a comparison as last argument.
+func (a analyzerImpl) IsNumericResult() bool {
+	return a.resultType == ResultTypeInt || a.resultType == ResultTypeFloat || a.resultType == ResultTypeDuration
+}
 
 // IsStringFlag determines the type of the CLI flag. It can either be Bool (default) or String.
 func (a analyzerImpl) IsStringFlag() bool { return a.isStringFlag }
@@ -72,7 +84,7 @@ func (a analyzerImpl) Clone() Analyzer {
 		version:                 a.version,
 		dependsOn:               a.dependsOn,
 		isStringFlag:            a.isStringFlag,
-		isBooleanResult:         a.isBooleanResult,
+		resultType:              a.resultType,
 		requiresParsingCommands: a.requiresParsingCommands,
 		requiresParsingMapData:  a.requiresParsingMapData,
 		argumentValidator:       a.argumentValidator,
@@ -87,8 +99,13 @@ func (a analyzerImpl) Clone() Analyzer {
 // should be shown to the client, but execution of the rest may continue.
 func (a *analyzerImpl) SetArguments(args []string) error {
 	var err error
-	a.args, err = a.argumentValidator.ValidateAndSet(args)
-	return err
+	if a.args, err = a.argumentValidator.ValidateAndSet(args); err != nil {
+		return err
+	}
+	if p, ok := a.analyzerProcessor.(argumentSetter); ok {
+		return p.SetArguments(a.args)
+	}
+	return nil
 }
 
 // ProcessCommand should be called for every command during a Replay analizing cycle.
@@ -106,13 +123,14 @@ func (a *analyzerImpl) ProcessCommand(command repcmd.Cmd) (bool, error) {
 
 // StartReadingReplay is called at the beginning of a Replay analyzing cycle.
 // Should not read anything from Commands; use ProcessCommand for that.
+// dependencyResults maps the Analyzer Names in DependsOn() to their finished Results on this Replay.
 // Returns true if the analyzer is finished calculating the result (i.e. no need
 // to process commands)
 // It may error, signaling that this Analyzer should no longer be used, and an error
 // should be shown to the client, but execution of the rest may continue.
-func (a *analyzerImpl) StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string) (bool, error) {
+func (a *analyzerImpl) StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, dependencyResults map[string]Result) (bool, error) {
 	var err error
-	a.result, a.done, err = a.analyzerProcessor.StartReadingReplay(replay, ctx, replayPath, a.args)
+	a.result, a.done, err = a.analyzerProcessor.StartReadingReplay(replay, ctx, replayPath, a.args, dependencyResults)
 	return a.done, err
 }
 
@@ -121,8 +139,8 @@ func newAnalyzerImpl(
 	description string,
 	version int,
 	dependsOn map[string]struct{},
-	isStringFlag,
-	isBooleanResult,
+	isStringFlag bool,
+	resultType ResultType,
 	requiresParsingCommands,
 	requiresParsingMapData bool,
 	argumentValidator argumentValidator,
@@ -135,7 +153,7 @@ func newAnalyzerImpl(
 		version:                 version,
 		dependsOn:               dependsOn,
 		isStringFlag:            isStringFlag,
-		isBooleanResult:         isBooleanResult,
+		resultType:              resultType,
 		requiresParsingCommands: requiresParsingCommands,
 		requiresParsingMapData:  requiresParsingMapData,
 		argumentValidator:       argumentValidator,
@@ -181,17 +199,17 @@ func (a *argumentValidatorMinutes) ValidateAndSet(args []string) ([]string, erro
 	return args, nil
 }
 
-type argumentValidator1v1Matchup struct{}
+type argumentValidatorMatchup struct{}
 
-func (a *argumentValidator1v1Matchup) ValidateAndSet(args []string) ([]string, error) {
-	if len(args) < 1 || len(args[0]) != 3 {
-		return []string{}, fmt.Errorf("please provide a valid 1v1 matchup e.g. TvZ")
+func (a *argumentValidatorMatchup) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide a valid matchup e.g. TvZ, PT vs ZZ or Z? v ?? (? is any race)")
 	}
-	args[0] = strings.ToUpper(args[0])
-	var res []string
-	res = append(res, string(args[0][0]), string(args[0][2]))
-	sort.Strings(res)
-	return res, nil
+	teams, ok := parseMatchup(args[0])
+	if !ok {
+		return []string{}, fmt.Errorf("invalid matchup %v; please use e.g. TvZ, PT vs ZZ or Z? v ?? (? is any race)", args[0])
+	}
+	return teams, nil
 }
 
 type argumentValidatorUnit struct{}
@@ -206,32 +224,196 @@ func (a *argumentValidatorUnit) ValidateAndSet(args []string) ([]string, error)
 	return []string{fmt.Sprintf("%v", nameToUnitID[args[0]])}, nil
 }
 
+type argumentValidatorUnitAndOptionalMinutes struct{}
+
+func (a *argumentValidatorUnitAndOptionalMinutes) ValidateAndSet(args []string) ([]string, error) {
+	unit, err := (&argumentValidatorUnit{}).ValidateAndSet(args)
+	if err != nil || len(args) < 2 {
+		return unit, err
+	}
+	if minutes, err := strconv.Atoi(args[1]); err != nil || minutes <= 0 {
+		return []string{}, fmt.Errorf("invalid number of minutes: %v; please provide a positive number", args[1])
+	}
+	return append(unit, args[1]), nil
+}
+
+var teamFormatRegexp = regexp.MustCompile(`^(\d+(v\d+)*|FFA\d+)$`)
+
+type argumentValidatorTeamFormats struct{}
+
+func (a *argumentValidatorTeamFormats) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide at least one team format e.g. 1v1, 2v2, 3v3, 2v2v2v2 or FFA4")
+	}
+	formats := make([]string, len(args))
+	for i, arg := range args {
+		formats[i] = strings.Replace(strings.ToUpper(strings.TrimSpace(arg)), "V", "v", -1)
+		if !teamFormatRegexp.MatchString(formats[i]) {
+			return []string{}, fmt.Errorf("invalid team format %v; please use e.g. 1v1, 2v2, 3v3, 2v2v2v2 or FFA4", arg)
+		}
+	}
+	return formats, nil
+}
+
+type argumentValidatorTech struct{}
+
+func (a *argumentValidatorTech) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide a valid tech name e.g. Stim Packs; valid names are: %v", strings.Join(techNames(), ", "))
+	}
+	name, ok := findEnumName(args[0], techNames())
+	if !ok {
+		return []string{}, fmt.Errorf("invalid tech name %v; valid names are: %v", args[0], strings.Join(techNames(), ", "))
+	}
+	return []string{name}, nil
+}
+
+type argumentValidatorUpgrade struct{}
+
+func (a *argumentValidatorUpgrade) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide a valid upgrade name e.g. Metabolic Boost; valid names are: %v", strings.Join(upgradeNames(), ", "))
+	}
+	name, ok := findEnumName(args[0], upgradeNames())
+	if !ok {
+		return []string{}, fmt.Errorf("invalid upgrade name %v; valid names are: %v", args[0], strings.Join(upgradeNames(), ", "))
+	}
+	return []string{name}, nil
+}
+
+type argumentValidatorOptionalCount struct {
+	defaultCount int
+}
+
+func (a *argumentValidatorOptionalCount) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{strconv.Itoa(a.defaultCount)}, nil
+	}
+	if count, err := strconv.Atoi(args[0]); err != nil || count <= 0 {
+		return []string{}, fmt.Errorf("invalid count: %v; please provide a positive number", args[0])
+	}
+	return args[:1], nil
+}
+
+type argumentValidatorOptionalMinutes struct {
+	defaultMinutes int
+}
+
+func (a *argumentValidatorOptionalMinutes) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{strconv.Itoa(a.defaultMinutes)}, nil
+	}
+	if minutes, err := strconv.Atoi(args[0]); err != nil || minutes <= 0 {
+		return []string{}, fmt.Errorf("invalid number of minutes: %v; please provide a positive number", args[0])
+	}
+	return args[:1], nil
+}
+
+type argumentValidatorOptionalDistance struct {
+	defaultDistance int
+}
+
+func (a *argumentValidatorOptionalDistance) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{strconv.Itoa(a.defaultDistance)}, nil
+	}
+	if distance, err := strconv.Atoi(args[0]); err != nil || distance <= 0 {
+		return []string{}, fmt.Errorf("invalid distance: %v; please provide a positive number of map tiles", args[0])
+	}
+	return args[:1], nil
+}
+
+type argumentValidatorPlayerNames struct{}
+
+func (a *argumentValidatorPlayerNames) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide at least one player name")
+	}
+	return args, nil
+}
+
+type argumentValidatorMapNames struct{}
+
+func (a *argumentValidatorMapNames) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide at least one map name")
+	}
+	return args, nil
+}
+
+type argumentValidatorRegexp struct{}
+
+// ValidateAndSet joins back the arguments, since the regular expression may contain commas. The regular expression is
+// compiled by analyzerProcessorRegexp.
+func (a *argumentValidatorRegexp) ValidateAndSet(args []string) ([]string, error) {
+	if len(args) < 1 {
+		return []string{}, fmt.Errorf("please provide a regular expression")
+	}
+	return []string{strings.Join(args, ",")}, nil
+}
+
 type analyzerProcessor interface {
-	StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, error)
-	ProcessCommand(command repcmd.Cmd, args []string, result string) (string, bool, error)
+	StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, error)
+	ProcessCommand(command repcmd.Cmd, args []string, result Result) (Result, bool, error)
 	Clone() analyzerProcessor
 }
 
 type analyzerProcessorImpl struct {
-	result             string
+	result             Result
 	done               bool
 	state              interface{}
-	startReadingReplay func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error)
-	processCommand     func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error)
+	startReadingReplay func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error)
+	processCommand     func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error)
 }
 
 func (a *analyzerProcessorImpl) Clone() analyzerProcessor {
-	return &analyzerProcessorImpl{"", false, nil, a.startReadingReplay, a.processCommand}
+	return &analyzerProcessorImpl{NewNullResult(), false, nil, a.startReadingReplay, a.processCommand}
 }
 
-func (a *analyzerProcessorImpl) StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, error) {
+func (a *analyzerProcessorImpl) StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, error) {
 	var err error
-	a.result, a.done, a.state, err = a.startReadingReplay(replay, ctx, replayPath, args)
+	a.result, a.done, a.state, err = a.startReadingReplay(replay, ctx, replayPath, args, dependencyResults)
 	return a.result, a.done, err
 }
 
-func (a *analyzerProcessorImpl) ProcessCommand(command repcmd.Cmd, args []string, result string) (string, bool, error) {
+func (a *analyzerProcessorImpl) ProcessCommand(command repcmd.Cmd, args []string, result Result) (Result, bool, error) {
 	var err error
 	a.result, a.done, err = a.processCommand(command, args, result, a.state)
 	return a.result, a.done, err
 }
+
+// argumentSetter is implemented by analyzerProcessors that prepare their state from the validated arguments once (e.g.
+// compiling them), rather than on every replay. SetArguments is called right after validating the arguments.
+type argumentSetter interface {
+	SetArguments(args []string) error
+}
+
+// analyzerProcessorRegexp is an analyzerProcessor for Analyzers with an argument validated by argumentValidatorRegexp,
+// whose result is known when starting to read the replay. The regular expression is compiled (ignoring case) when
+// setting the arguments, and cloned along with the processor, so it isn't compiled again on every replay.
+type analyzerProcessorRegexp struct {
+	regexp             *regexp.Regexp
+	startReadingReplay func(replay *rep.Replay, ctx Context, replayPath string, r *regexp.Regexp, dependencyResults map[string]Result) (Result, error)
+}
+
+func (a *analyzerProcessorRegexp) SetArguments(args []string) error {
+	r, err := regexp.Compile("(?i)" + args[0])
+	if err != nil {
+		return fmt.Errorf("invalid regular expression %v: %v", args[0], err)
+	}
+	a.regexp = r
+	return nil
+}
+
+func (a *analyzerProcessorRegexp) Clone() analyzerProcessor {
+	return &analyzerProcessorRegexp{a.regexp, a.startReadingReplay}
+}
+
+func (a *analyzerProcessorRegexp) StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, error) {
+	result, err := a.startReadingReplay(replay, ctx, replayPath, a.regexp, dependencyResults)
+	return result, true, err
+}
+
+func (a *analyzerProcessorRegexp) ProcessCommand(command repcmd.Cmd, args []string, result Result) (Result, bool, error) {
+	return result, true, nil
+}
@@ -2,7 +2,10 @@ package analyzer
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -30,15 +33,18 @@ type Analyzer interface {
 	SetArguments(args []string) error
 
 	// DependsOn are the Analyzer Name's whose Results this Analyzer depends on: for building DAG.
+	// The Executor runs dependencies (without arguments) before this Analyzer, instantiating them if they
+	// weren't requested, and hands their Results to StartReadingReplay.
 	DependsOn() map[string]struct{}
 
 	// StartReadingReplay is called at the beginning of a Replay analyzing cycle.
 	// Should not read anything from Commands; use ProcessCommand for that.
+	// dependencyResults maps the Analyzer Names in DependsOn() to their finished Results on this Replay.
 	// Returns true if the analyzer is finished calculating the result (i.e. no need
 	// to process commands)
 	// It may error, signaling that this Analyzer should no longer be used, and an error
 	// should be shown to the client, but execution of the rest may continue.
-	StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string) (bool, error)
+	StartReadingReplay(replay *rep.Replay, ctx Context, replayPath string, dependencyResults map[string]Result) (bool, error)
 
 	// ProcessCommand should be called for every command during a Replay analizing cycle.
 	// StartReadingReplay should be called before processing any command, to refresh
@@ -51,7 +57,7 @@ type Analyzer interface {
 
 	// IsDone Returns true if the analyzer is finished calculating the result, and
 	// returns it. Shouldn't be called before calling StartReadingReplay.
-	IsDone() (string, bool)
+	IsDone() (Result, bool)
 
 	// Version is useful for managing updates to an Analyzer: whenever an update is made to an
 	// analyzer, the Version should be numerically higher. Then, if there's a cached
@@ -61,10 +67,18 @@ type Analyzer interface {
 	// IsStringFlag determines the type of the CLI flag. It can either be Bool (default) or String.
 	IsStringFlag() bool
 
+	// ResultType is the type of the Result of this Analyzer. Note that Results can also be null, e.g. when the -me
+	// player is not in the replay.
+	ResultType() ResultType
+
 	// IsBooleanResult Determines if the result type is "true"/"false". Used for providing -filter-- and -filter-not--
 	// flags.
 	IsBooleanResult() bool
 
+	// IsNumericResult Determines if the result type is a number (int, float or duration). Used for providing
+	// -filter-- and -filter-not-- flags whose last argument is a comparison, e.g. -filter--my-apm '>=150'.
+	IsNumericResult() bool
+
 	// Clone is a convenience method just so there can be a map[string]analyzer.Analyzer in createSortedAnalyzerWrappers
 	Clone() Analyzer
 
@@ -78,12 +92,24 @@ type Analyzer interface {
 // Context is all context necessary for analyzers to properly analyze a replay
 type Context struct {
 	Me map[string]struct{}
+
+	// Openings are the rules for classifying openings in my-opening. If nil, DefaultOpenings are used.
+	Openings []Opening
+
+	// Maps is the catalog of known maps for canonical-map.
+	Maps []KnownMap
+
+	// MapNameRules are the regular expressions whose matches map-name-normalized removes from map names. If nil,
+	// DefaultMapNameRules are used.
+	MapNameRules []*regexp.Regexp
+
+	player *rep.Player // set by the Executor in per-player mode, overriding Me
 }
 
 // NewContext creates an Analyzer Context. Context should be everything unrelated to a replay that an Analyzer should
 // know in order to analyze a replay e.g. who is the -me player
 func NewContext(me map[string]struct{}) Context {
-	return Context{me}
+	return Context{Me: me}
 }
 
 // Executor is the main struct the client should interact with: it receives a list of replays and analyzer
@@ -91,30 +117,91 @@ func NewContext(me map[string]struct{}) Context {
 type Executor struct {
 	replayPaths                []string
 	analyzerWrappers           []analyzerWrapper
+	evaluationOrder            []int
 	ctx                        Context
 	output                     Output
 	copyPath                   string
 	shouldCopyToOutputLocation bool
 	requiresParsingCommands    bool
 	requiresParsingMapData     bool
+	workers                    int
+	cache                      *resultCache
+	where                      whereExpression
+	perPlayer                  bool
+}
+
+// ExecutorOption configures an optional behaviour of an Executor. See the With* functions.
+type ExecutorOption func(*executorOptions)
+
+type executorOptions struct {
+	workers   int
+	cacheDir  string
+	where     string
+	perPlayer bool
+}
+
+// WithWorkers makes the Executor parse and analyze replays concurrently with the given number of workers (by default,
+// or if workers <= 0, GOMAXPROCS). Results are always output in replay path order.
+func WithWorkers(workers int) ExecutorOption {
+	return func(o *executorOptions) { o.workers = workers }
+}
+
+// WithCacheDir makes the Executor cache results in the given directory by replay contents and Analyzer Version, so
+// that replays are only parsed again if they change, or if an Analyzer is updated or requested for the first time.
+// An empty cacheDir means no caching.
+func WithCacheDir(cacheDir string) ExecutorOption {
+	return func(o *executorOptions) { o.cacheDir = cacheDir }
+}
+
+// WithWhere makes the Executor only output replays matching the given expression, e.g. "is-1v1 and not
+// my-matchup-is(TvZ)". See parseWhere for its syntax. An empty where matches all replays.
+func WithWhere(where string) ExecutorOption {
+	return func(o *executorOptions) { o.where = where }
+}
+
+// WithPerPlayer makes the Executor output a row per player (excluding observers) per replay rather than a row per
+// replay, in which the -me player is that player, e.g. my-race is the race of each player.
+func WithPerPlayer(perPlayer bool) ExecutorOption {
+	return func(o *executorOptions) { o.perPlayer = perPlayer }
 }
 
 // NewExecutor should be the entrypoint of this library to the client. It creates an Executor.
 // It may return several errors: replay paths may not exist, analyzer requests may be for unknown analyzers,
 // copy path may not exist, etc.
-func NewExecutor(replayPaths []string, analyzerRequests [][]string, ctx Context, output Output, copyPath string) (*Executor, []error) {
+// Optional behaviours are configured with ExecutorOptions, e.g. WithWorkers(4).
+func NewExecutor(replayPaths []string, analyzerRequests [][]string, ctx Context, output Output, copyPath string, options ...ExecutorOption) (*Executor, []error) {
+	opts := executorOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
 	var (
 		errs, rpErrs, aeErrs []error
 		ae                   = &Executor{}
+		whereAtoms           []*whereAtom
 	)
+	if opts.where != "" {
+		var err error
+		if ae.where, whereAtoms, err = parseWhere(opts.where); err != nil {
+			errs = append(errs, fmt.Errorf("invalid -where expression: %v", err))
+		}
+	}
 	ae.replayPaths, rpErrs = ae.filterReplayPaths(replayPaths)
-	ae.analyzerWrappers, aeErrs = ae.createSortedAnalyzerWrappers(analyzerRequests)
+	ae.analyzerWrappers, aeErrs = ae.createSortedAnalyzerWrappers(analyzerRequests, whereAtoms)
+	if len(whereAtoms) > 0 && !areWhereAtomsInstantiated(whereAtoms) {
+		ae.where = nil // N.B. errors were reported while creating analyzerWrappers
+	}
+	ae.evaluationOrder = sortTopologically(ae.analyzerWrappers)
 	ae.requiresParsingCommands, ae.requiresParsingMapData = ae.determineRequiredParsingSections()
 	ae.ctx = ctx
+	ae.perPlayer = opts.perPlayer
 	ae.output = output
 	if ae.output == nil {
 		ae.output = NewNoOutput()
 	}
+	ae.workers = opts.workers
+	if ae.workers <= 0 {
+		ae.workers = runtime.GOMAXPROCS(0)
+	}
 	if copyPath != "" {
 		ae.shouldCopyToOutputLocation = true
 		if ok, err := isFileExist(copyPath); !ok || err != nil {
@@ -128,6 +215,13 @@ func NewExecutor(replayPaths []string, analyzerRequests [][]string, ctx Context,
 		}
 		ae.copyPath = copyPath
 	}
+	if opts.cacheDir != "" {
+		if err := os.MkdirAll(opts.cacheDir, 0755); err != nil {
+			errs = append(errs, fmt.Errorf("error creating cache directory (%v): %v", opts.cacheDir, err))
+		} else {
+			ae.cache = newResultCache(opts.cacheDir, ae.analyzerWrappers)
+		}
+	}
 	errs = append(errs, rpErrs...)
 	errs = append(errs, aeErrs...)
 	return ae, errs
@@ -149,35 +243,35 @@ func (e *Executor) Execute() []error {
 // analyzer, so it doesn't necessarily mean that the complete result is unusable.
 // Usually, when using this method, the Executor's output should be NoOutput, since the results are
 // available in the return value of this method.
-func (e *Executor) ExecuteWithResults() ([][]string, []error) {
+func (e *Executor) ExecuteWithResults() ([][]Result, []error) {
 	results, errs := e.execute(true)
 	return results, errs
 }
 
-func (e *Executor) execute(saveResults bool) ([][]string, []error) {
+func (e *Executor) execute(saveResults bool) ([][]Result, []error) {
 	var (
-		results [][]string
+		results [][]Result
 		errs    []error
 	)
 	if err := e.output.Pre(e.analyzerWrappers); err != nil { // CSV/JSON setup
-		errs = append(errs, err)
+		return nil, []error{err} // N.B. the output can't write any results, e.g. AggregateOutput is missing analyzers
 	}
-	for _, replayPath := range e.replayPaths {
-		r, err := e.parseReplayFile(replayPath)
-		if err != nil {
-			errs = append(errs, err)
+	for pendingOutcome := range e.analyzeReplays() {
+		var (
+			outcome    = <-pendingOutcome
+			replayPath = outcome.replayPath
+		)
+		errs = append(errs, outcome.errs...)
+		if len(outcome.rows) == 0 {
 			continue
 		}
-		replayResult, erErrs := e.executeReplay(r, replayPath, e.cloneAnalyzerWrappers())
-		errs = append(errs, erErrs...)
-		if len(replayResult) == 0 {
-			continue
-		}
-		if err := e.output.ReplayResults(replayResult); err != nil { // CSV/JSON write line
-			errs = append(errs, err)
-		}
-		if saveResults { // If used as library
-			results = append(results, replayResult)
+		for _, replayResult := range outcome.rows {
+			if err := e.output.ReplayResults(replayResult); err != nil { // CSV/JSON write line
+				errs = append(errs, err)
+			}
+			if saveResults { // If used as library
+				results = append(results, e.withoutHiddenResults(replayResult))
+			}
 		}
 		if e.shouldCopyToOutputLocation {
 			if err := copyFile(replayPath, fmt.Sprintf("%v/%v", e.copyPath, filepath.Base(replayPath))); err != nil {
@@ -191,65 +285,266 @@ func (e *Executor) execute(saveResults bool) ([][]string, []error) {
 	return results, errs
 }
 
-func (e Executor) executeReplay(r *rep.Replay, replayPath string, analyzerWrappers []analyzerWrapper) ([]string, []error) {
+// replayOutcome has the results of a replay: one row, or a row per player in per-player mode, excluding those
+// excluded by filters.
+type replayOutcome struct {
+	replayPath string
+	rows       [][]Result
+	errs       []error
+}
+
+// analyzeReplays parses and analyzes all replays on e.workers goroutines. Each replay's outcome is delivered on its
+// own channel, and those channels are sent in replay path order, so the caller can consume outcomes in a
+// deterministic order while later replays are still being analyzed. At most ~2*e.workers outcomes are in flight.
+func (e *Executor) analyzeReplays() <-chan chan replayOutcome {
 	var (
-		results      = make([]string, len(analyzerWrappers))
-		errs         = []error{}
-		removedCount = 0
+		outcomes = make(chan chan replayOutcome, e.workers)
+		sem      = make(chan struct{}, e.workers)
 	)
+	go func() {
+		defer close(outcomes)
+		for _, replayPath := range e.replayPaths {
+			sem <- struct{}{}
+			outcome := make(chan replayOutcome, 1)
+			outcomes <- outcome
+			go func(replayPath string) {
+				defer func() { <-sem }()
+				outcome <- e.analyzeReplay(replayPath)
+			}(replayPath)
+		}
+	}()
+	return outcomes
+}
 
-	// Analyze everything except Commands; try to finish early
-	for i, aw := range analyzerWrappers {
-		done, err := aw.analyzer.StartReadingReplay(r, e.ctx, replayPath)
-		if err != nil {
-			errs = append(errs,
-				fmt.Errorf("error beginning to read replay %v with Analyzer %v: %v", replayPath,
-					aw.analyzer.Name(), err))
+func (e *Executor) analyzeReplay(replayPath string) replayOutcome {
+	var (
+		outcome = replayOutcome{replayPath: replayPath}
+		entry   cacheEntry
+		r       *rep.Replay // N.B. only parsed if some results aren't cached
+		ctxs    = []Context{e.ctx}
+	)
+	if e.cache != nil {
+		var err error
+		if entry, err = e.cache.load(replayPath); err != nil {
+			outcome.errs = append(outcome.errs, err) // N.B. not fatal: the replay will just be analyzed
+		}
+	}
+	if e.perPlayer {
+		var ok bool
+		if ctxs, ok = e.cachedPlayerContexts(entry, replayPath); !ok {
+			header, err := e.parseReplayHeader(replayPath)
+			if err != nil {
+				outcome.errs = append(outcome.errs, err)
+				return outcome
+			}
+			ctxs = e.playerContexts(header)
+			for _, ctx := range ctxs {
+				entry.playerIDs = append(entry.playerIDs, ctx.player.ID)
+			}
+		}
+	}
+	for _, ctx := range ctxs {
+		analysis := newReplayAnalysis(e.analyzerWrappers, e.where)
+		if e.cache != nil {
+			e.cache.restore(entry, analysis, ctx, replayPath)
+		}
+		if !analysis.isExcluded() && !analysis.isDone() {
+			if r == nil {
+				var err error
+				if r, err = e.parseReplayFile(replayPath); err != nil {
+					outcome.errs = append(outcome.errs, err)
+					return outcome
+				}
+			}
+			e.executeReplay(r, replayPath, ctx, e.cloneAnalyzerWrappers(), analysis)
+			if e.cache != nil {
+				if err := e.cache.save(entry, analysis, ctx, replayPath); err != nil {
+					outcome.errs = append(outcome.errs, err)
+				}
+			}
 		}
-		if done {
-			results[i], _ = aw.analyzer.IsDone()
+		outcome.errs = append(outcome.errs, analysis.nonNilErrs()...)
+		if !analysis.isExcluded() {
+			outcome.rows = append(outcome.rows, analysis.results)
 		}
-		if done && ((aw.isFilterNot && results[i] == "true") || (aw.isFilter && results[i] != "true")) {
-			return []string{}, errs // Optimization: move to next replay if excluded by any filters already
+	}
+	return outcome
+}
+
+// playerContexts returns a Context per player of the replay for per-player mode, excluding observers.
+func (e *Executor) playerContexts(header *rep.Header) []Context {
+	ctxs := []Context{}
+	for _, p := range header.Players {
+		if isObserver(p) {
+			continue
 		}
-		aw.removed = done || err != nil // if analyzer is done or had error, signal that commands needn't be processed
-		if aw.removed {
-			removedCount++
+		ctx := e.ctx
+		ctx.player = p
+		ctxs = append(ctxs, ctx)
+	}
+	return ctxs
+}
+
+// cachedPlayerContexts returns a Context per player of the replay for per-player mode, from the player IDs in the
+// cache, if the cache has every player's results (or a filter excludes them); otherwise the replay's header has to be
+// parsed. N.B. these Contexts' players only have an ID, which is all that restoring from the cache needs.
+func (e *Executor) cachedPlayerContexts(entry cacheEntry, replayPath string) ([]Context, bool) {
+	if e.cache == nil || entry.playerIDs == nil {
+		return nil, false
+	}
+	ctxs := make([]Context, len(entry.playerIDs))
+	for i, playerID := range entry.playerIDs {
+		ctxs[i] = e.ctx
+		ctxs[i].player = &rep.Player{ID: playerID}
+		analysis := newReplayAnalysis(e.analyzerWrappers, e.where)
+		e.cache.restore(entry, analysis, ctxs[i], replayPath)
+		if !analysis.isExcluded() && !analysis.isDone() {
+			return nil, false
 		}
 	}
+	return ctxs, true
+}
+
+// replayAnalysis holds the results of analyzing a replay, by analyzerWrapper pos.
+type replayAnalysis struct {
+	analyzerWrappers []analyzerWrapper
+	results          []Result
+	errs             []error // errors of each analyzer; nil if there was no error
+	done             []bool
+	where            whereExpression // nil if there's no -where expression
+	excluded         bool            // true if any filter or the -where expression excluded the replay
+}
+
+func newReplayAnalysis(analyzerWrappers []analyzerWrapper, where whereExpression) *replayAnalysis {
+	return &replayAnalysis{
+		analyzerWrappers: analyzerWrappers,
+		where:            where,
+		results:          make([]Result, len(analyzerWrappers)),
+		errs:             make([]error, len(analyzerWrappers)),
+		done:             make([]bool, len(analyzerWrappers)),
+	}
+}
+
+// finish records the final result of an analyzer, and whether it excludes the replay.
+func (a *replayAnalysis) finish(pos int, result Result, err error) {
+	a.results[pos], a.errs[pos], a.done[pos] = result, err, true
+	a.excluded = a.excluded || a.analyzerWrappers[pos].excludes(result)
+	if a.analyzerWrappers[pos].whereAtom != nil && !a.excluded {
+		if matches, known := a.where.eval(a); known && !matches {
+			a.excluded = true
+		}
+	}
+}
+
+func (a *replayAnalysis) isExcluded() bool { return a.excluded }
+
+func (a *replayAnalysis) isDone() bool {
+	for _, done := range a.done {
+		if !done {
+			return false
+		}
+	}
+	return true
+}
 
-	// Analyze Commands. N.B. This is the expensive loop in the algorithm; optimize here!
-	commands := []repcmd.Cmd{}
-	if e.requiresParsingCommands {
-		commands = r.Commands.Cmds
+func (a *replayAnalysis) nonNilErrs() []error {
+	errs := []error{}
+	for _, err := range a.errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	for _, c := range commands {
-		if len(analyzerWrappers) == removedCount {
-			break // Optimization: don't loop over commands if there's nothing to do!
+	return errs
+}
+
+// executeReplay runs all analyzers that are not done yet in the given replayAnalysis (e.g. because their results
+// were cached) on the replay, until they are done or a filter excludes the replay.
+func (e Executor) executeReplay(r *rep.Replay, replayPath string, ctx Context, analyzerWrappers []analyzerWrapper, analysis *replayAnalysis) {
+	var (
+		started      = make([]bool, len(analyzerWrappers))
+		startedCount = 0
+	)
+	for i := range analyzerWrappers {
+		if analysis.done[i] {
+			started[i] = true
+			startedCount++
 		}
-		for i, aw := range analyzerWrappers {
-			if aw.removed {
+	}
+
+	// Analyzers can only start once all their dependencies are done, so analyzing is done in passes: each pass
+	// starts all analyzers whose dependencies are done and feeds them commands until they are done. Most analyzers
+	// finish on StartReadingReplay, so usually there is only one pass over commands, or none at all.
+	for startedCount < len(analyzerWrappers) {
+		pendingCount := 0
+
+		// Analyze everything except Commands; try to finish early
+		for _, i := range e.evaluationOrder {
+			aw := analyzerWrappers[i]
+			if started[i] || !aw.areDependenciesDone(analysis.done) {
 				continue
 			}
-			done, err := aw.analyzer.ProcessCommand(c)
+			started[i] = true
+			startedCount++
+			isDone, err := aw.analyzer.StartReadingReplay(r, ctx, replayPath, aw.dependencyResults(analysis.results))
 			if err != nil {
-				errs = append(errs,
-					fmt.Errorf("error reading command on replay %v with Analyzer %v: %v",
-						replayPath, aw.analyzer.Name(), err))
+				err = fmt.Errorf("error beginning to read replay %v with Analyzer %v: %v", replayPath,
+					aw.analyzer.Name(), err)
 			}
-			if done {
-				results[i], _ = aw.analyzer.IsDone()
+			if !isDone && err == nil {
+				pendingCount++
+				continue
 			}
-			if done && ((aw.isFilterNot && results[i] == "true") || (aw.isFilter && results[i] != "true")) {
-				return []string{}, errs // Optimization: move to next replay if excluded by any filters already
+			result, _ := aw.analyzer.IsDone()
+			analysis.finish(i, result, err) // if analyzer is done or had error, signal that commands needn't be processed
+			if analysis.isExcluded() {
+				return // Optimization: move to next replay if excluded by any filters already
 			}
-			aw.removed = done || err != nil // if analyzer is done or had error, signal that more commands needn't be processed
-			if aw.removed {
-				removedCount++
+		}
+
+		// Analyze Commands. N.B. This is the expensive loop in the algorithm; optimize here!
+		commands := []repcmd.Cmd{}
+		if e.requiresParsingCommands {
+			commands = r.Commands.Cmds
+		}
+		for _, c := range commands {
+			if pendingCount == 0 {
+				break // Optimization: don't loop over commands if there's nothing to do!
+			}
+			for _, i := range e.evaluationOrder {
+				aw := analyzerWrappers[i]
+				if !started[i] || analysis.done[i] {
+					continue
+				}
+				isDone, err := aw.analyzer.ProcessCommand(c)
+				if err != nil {
+					err = fmt.Errorf("error reading command on replay %v with Analyzer %v: %v",
+						replayPath, aw.analyzer.Name(), err)
+				}
+				if !isDone && err == nil {
+					continue
+				}
+				result, _ := aw.analyzer.IsDone()
+				analysis.finish(i, result, err) // if analyzer is done or had error, signal that more commands needn't be processed
+				pendingCount--
+				if analysis.isExcluded() {
+					return // Optimization: move to next replay if excluded by any filters already
+				}
+			}
+		}
+
+		// No more commands: whatever result the remaining analyzers of this pass have is final
+		for _, i := range e.evaluationOrder {
+			aw := analyzerWrappers[i]
+			if !started[i] || analysis.done[i] {
+				continue
+			}
+			result, _ := aw.analyzer.IsDone()
+			analysis.finish(i, result, nil)
+			if analysis.isExcluded() {
+				return
 			}
 		}
 	}
-	return results, errs
 }
 
 func (e Executor) parseReplayFile(replayPath string) (*rep.Replay, error) {
@@ -263,26 +558,90 @@ func (e Executor) parseReplayFile(replayPath string) (*rep.Replay, error) {
 	return r, nil
 }
 
+// parseReplayHeader only parses the replay's header, e.g. to know its players before knowing if the replay needs to
+// be parsed at all.
+func (e Executor) parseReplayHeader(replayPath string) (*rep.Header, error) {
+	r, err := repparser.ParseFileSections(replayPath, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("screp failed to parse replay %v: %v", replayPath, err)
+	}
+	return r.Header, nil
+}
+
 type analyzerWrapper struct {
-	analyzer    Analyzer
-	isFilter    bool
-	isFilterNot bool
-	displayName string
-	name        string
-	pos         int
-	removed     bool // used to signal that commands needn't be processed
+	analyzer     Analyzer
+	isFilter     bool
+	isFilterNot  bool
+	isHidden     bool        // only instantiated because another analyzer depends on it, so it's not output
+	whereAtom    *whereAtom  // non-nil if it computes an atom of the -where expression; also hidden
+	comparison   *comparison // for filters and -where atoms on numeric Results; otherwise they must be true
+	displayName  string
+	name         string
+	pos          int
+	dependencies map[string]int // Analyzer Name => pos of the analyzerWrapper that provides its results
 }
 
 func (w analyzerWrapper) less(w2 analyzerWrapper) bool {
-	return ((w.isFilter || w.isFilterNot) && !w2.isFilter && !w2.isFilterNot) ||
-		(((w.isFilter || w.isFilterNot) == (w2.isFilter || w2.isFilterNot)) && w.analyzer.Name() < w2.analyzer.Name())
+	if w.rank() != w2.rank() {
+		return w.rank() < w2.rank()
+	}
+	return w.analyzer.Name() < w2.analyzer.Name()
+}
+
+// rank sorts filters (including -where atoms) first, so that they are evaluated first, then output columns, and
+// then dependencies.
+func (w analyzerWrapper) rank() int {
+	switch {
+	case w.isFilter || w.isFilterNot || w.whereAtom != nil:
+		return 0
+	case w.isHidden:
+		return 2
+	}
+	return 1
 }
 
 func (w analyzerWrapper) clone() analyzerWrapper {
-	return analyzerWrapper{w.analyzer.Clone(), w.isFilter, w.isFilterNot, w.displayName, w.name, w.pos, false}
+	return analyzerWrapper{w.analyzer.Clone(), w.isFilter, w.isFilterNot, w.isHidden, w.whereAtom, w.comparison, w.displayName, w.name, w.pos, w.dependencies}
 }
 
-func (e Executor) createSortedAnalyzerWrappers(analyzerRequests [][]string) ([]analyzerWrapper, []error) {
+// excludes returns true if this analyzerWrapper is a filter and the given result excludes the replay.
+func (w analyzerWrapper) excludes(result Result) bool {
+	return (w.isFilterNot && w.matches(result)) || (w.isFilter && !w.matches(result))
+}
+
+// matches returns true if the given result satisfies this analyzerWrapper as a filter or -where atom, i.e. it's true
+// or it satisfies its comparison.
+func (w analyzerWrapper) matches(result Result) bool {
+	if w.comparison != nil {
+		return w.comparison.matches(result)
+	}
+	return result.IsTrue()
+}
+
+func (w analyzerWrapper) areDependenciesDone(done []bool) bool {
+	for _, pos := range w.dependencies {
+		if !done[pos] {
+			return false
+		}
+	}
+	return true
+}
+
+func (w analyzerWrapper) dependencyResults(results []Result) map[string]Result {
+	dependencyResults := make(map[string]Result, len(w.dependencies))
+	for name, pos := range w.dependencies {
+		dependencyResults[name] = results[pos]
+	}
+	return dependencyResults
+}
+
+// isDependencyFor returns true if this analyzerWrapper's results can be handed to analyzers depending on name,
+// i.e. it's the Analyzer with that name and without arguments.
+func (w analyzerWrapper) isDependencyFor(name string) bool {
+	return w.analyzer.Name() == name && w.displayName == name
+}
+
+func (e Executor) createSortedAnalyzerWrappers(analyzerRequests [][]string, whereAtoms []*whereAtom) ([]analyzerWrapper, []error) {
 	var (
 		analyzerWrappers = []analyzerWrapper{}
 		errs             = []error{}
@@ -299,37 +658,244 @@ func (e Executor) createSortedAnalyzerWrappers(analyzerRequests [][]string) ([]a
 			analyzerRequest[0] = analyzerRequest[0][len("filter-not--"):]
 			isFilterNot = true
 		}
-		_analyzer, ok := Analyzers[analyzerRequest[0]]
-		if !ok {
-			errs = append(errs, fmt.Errorf("analyzer for name %v not found; ignoring", analyzerRequest[0]))
-			continue
+		var (
+			w   analyzerWrapper
+			err error
+		)
+		if isFilter || isFilterNot {
+			w, err = newFilterAnalyzerWrapper(analyzerRequest, i)
+		} else {
+			w, err = newAnalyzerWrapper(analyzerRequest, i)
 		}
-		an := _analyzer.Clone()
-		if err := an.SetArguments(analyzerRequest[1:]); err != nil {
-			errs = append(errs, fmt.Errorf("error setting arguments for analyzer %v: %v; ignoring", an.Name(), err))
+		if err != nil {
+			errs = append(errs, err)
 			continue
 		}
-		displayName := an.Name()
-		if len(analyzerRequest[1:]) > 0 {
-			displayName = fmt.Sprintf("%v(%v)", an.Name(), strings.Join(analyzerRequest[1:], ","))
+		w.isFilter, w.isFilterNot = isFilter, isFilterNot
+		analyzerWrappers = append(analyzerWrappers, w)
+	}
+	for i, atom := range whereAtoms {
+		atom.pos = -1
+		w, err := newFilterAnalyzerWrapper(atom.analyzerRequest, len(analyzerRequests)+i)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid -where expression: %v", err))
+			continue
 		}
-		analyzerWrappers = append(analyzerWrappers, analyzerWrapper{
-			analyzer:    an,
-			name:        fmt.Sprintf("%v_%v", an.Name(), i),
-			displayName: displayName,
-			isFilter:    isFilter,
-			isFilterNot: isFilterNot,
-		})
+		w.isHidden, w.whereAtom = true, atom
+		analyzerWrappers = append(analyzerWrappers, w)
 	}
+	analyzerWrappers, depErrs := e.addDependencies(analyzerWrappers, len(analyzerRequests)+len(whereAtoms))
+	errs = append(errs, depErrs...)
 	sort.Slice(analyzerWrappers, func(i, j int) bool {
 		return analyzerWrappers[i].less(analyzerWrappers[j])
 	})
 	for i := range analyzerWrappers {
 		analyzerWrappers[i].pos = i
+		if analyzerWrappers[i].whereAtom != nil {
+			analyzerWrappers[i].whereAtom.pos = i
+		}
+	}
+	for i := range analyzerWrappers {
+		analyzerWrappers[i].dependencies = map[string]int{}
+		for name := range analyzerWrappers[i].analyzer.DependsOn() {
+			for _, w := range analyzerWrappers {
+				if w.isDependencyFor(name) {
+					analyzerWrappers[i].dependencies[name] = w.pos
+					break
+				}
+			}
+		}
+	}
+	return analyzerWrappers, errs
+}
+
+// newAnalyzerWrapper instantiates the Analyzer of an analyzer request, i.e. its name followed by its arguments.
+// i makes its name unique.
+func newAnalyzerWrapper(analyzerRequest []string, i int) (analyzerWrapper, error) {
+	_analyzer, ok := Analyzers[analyzerRequest[0]]
+	if !ok {
+		return analyzerWrapper{}, fmt.Errorf("analyzer for name %v not found; ignoring", analyzerRequest[0])
+	}
+	an := _analyzer.Clone()
+	if err := an.SetArguments(analyzerRequest[1:]); err != nil {
+		return analyzerWrapper{}, fmt.Errorf("error setting arguments for analyzer %v: %v; ignoring", an.Name(), err)
+	}
+	if cycle := findDependencyCycle(an.Name(), an.DependsOn(), nil); len(cycle) > 0 {
+		return analyzerWrapper{}, fmt.Errorf("analyzer %v has a dependency cycle: %v; ignoring", an.Name(), strings.Join(cycle, " -> "))
+	}
+	return analyzerWrapper{
+		analyzer:    an,
+		name:        fmt.Sprintf("%v_%v", an.Name(), i),
+		displayName: analyzerRequestDisplayName(analyzerRequest),
+	}, nil
+}
+
+// newFilterAnalyzerWrapper instantiates the Analyzer of a filter or -where atom request. The Analyzer must have a
+// true/false Result, or a numeric Result with a comparison as the request's last argument, e.g. ">=150".
+func newFilterAnalyzerWrapper(analyzerRequest []string, i int) (analyzerWrapper, error) {
+	_analyzer, ok := Analyzers[analyzerRequest[0]]
+	if !ok {
+		return analyzerWrapper{}, fmt.Errorf("analyzer for name %v not found; ignoring", analyzerRequest[0])
+	}
+	if _analyzer.IsBooleanResult() {
+		return newAnalyzerWrapper(analyzerRequest, i)
+	}
+	if !_analyzer.IsNumericResult() {
+		return analyzerWrapper{}, fmt.Errorf("analyzer %v has neither a true/false nor a numeric result, so it cannot filter; ignoring", analyzerRequest[0])
+	}
+	if len(analyzerRequest) < 2 {
+		return analyzerWrapper{}, fmt.Errorf("filtering on analyzer %v requires a comparison as last argument, e.g. >=150; ignoring", analyzerRequest[0])
+	}
+	c, err := parseComparison(analyzerRequest[len(analyzerRequest)-1])
+	if err != nil {
+		return analyzerWrapper{}, fmt.Errorf("error setting arguments for analyzer %v: %v; ignoring", analyzerRequest[0], err)
+	}
+	w, err := newAnalyzerWrapper(analyzerRequest[:len(analyzerRequest)-1], i)
+	if err != nil {
+		return w, err
+	}
+	w.comparison = &c
+	return w, nil
+}
+
+// areWhereAtomsInstantiated returns true if all atoms of the -where expression have an analyzerWrapper computing them.
+// Otherwise, the expression cannot be evaluated.
+func areWhereAtomsInstantiated(whereAtoms []*whereAtom) bool {
+	for _, atom := range whereAtoms {
+		if atom.pos < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// addDependencies adds a hidden analyzerWrapper for every Analyzer that is depended on but wasn't requested (i.e.
+// with no arguments). Analyzers whose dependencies cannot be instantiated are removed.
+func (e Executor) addDependencies(analyzerWrappers []analyzerWrapper, nextIndex int) ([]analyzerWrapper, []error) {
+	var (
+		errs   = []error{}
+		broken = map[string]struct{}{} // Analyzer Names that cannot be instantiated
+	)
+	for i := 0; i < len(analyzerWrappers); i++ { // N.B. analyzerWrappers grows while iterating
+		for name := range analyzerWrappers[i].analyzer.DependsOn() {
+			if _, ok := broken[name]; ok || hasDependencyFor(analyzerWrappers, name) {
+				continue
+			}
+			_analyzer, ok := Analyzers[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("analyzer %v depends on analyzer %v, which is not found", analyzerWrappers[i].analyzer.Name(), name))
+				broken[name] = struct{}{}
+				continue
+			}
+			an := _analyzer.Clone()
+			if err := an.SetArguments([]string{}); err != nil {
+				errs = append(errs, fmt.Errorf("analyzer %v depends on analyzer %v, which requires arguments: %v", analyzerWrappers[i].analyzer.Name(), name, err))
+				broken[name] = struct{}{}
+				continue
+			}
+			analyzerWrappers = append(analyzerWrappers, analyzerWrapper{
+				analyzer:    an,
+				name:        fmt.Sprintf("%v_%v", an.Name(), nextIndex),
+				displayName: an.Name(),
+				isHidden:    true,
+			})
+			nextIndex++
+		}
+	}
+	for len(broken) > 0 { // Remove analyzers that depend on broken ones, and so on
+		var (
+			kept      = []analyzerWrapper{}
+			newBroken = map[string]struct{}{}
+		)
+		for _, w := range analyzerWrappers {
+			if dependsOnAny(w.analyzer, broken) {
+				errs = append(errs, fmt.Errorf("analyzer %v cannot be computed because of its dependencies; ignoring", w.displayName))
+				if w.isDependencyFor(w.analyzer.Name()) {
+					newBroken[w.analyzer.Name()] = struct{}{}
+				}
+				continue
+			}
+			kept = append(kept, w)
+		}
+		analyzerWrappers, broken = kept, newBroken
 	}
 	return analyzerWrappers, errs
 }
 
+func hasDependencyFor(analyzerWrappers []analyzerWrapper, name string) bool {
+	for _, w := range analyzerWrappers {
+		if w.isDependencyFor(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func dependsOnAny(a Analyzer, names map[string]struct{}) bool {
+	for name := range a.DependsOn() {
+		if _, ok := names[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findDependencyCycle walks the DependsOn graph of the Analyzers map starting from the given Analyzer Name, and
+// returns the first cycle found as a list of Analyzer Names (first and last being the same), or nil.
+func findDependencyCycle(name string, dependsOn map[string]struct{}, path []string) []string {
+	for i, visited := range path {
+		if visited == name {
+			return append(cloneStringSlice(path[i:]), name)
+		}
+	}
+	path = append(path, name)
+	names := make([]string, 0, len(dependsOn))
+	for dependency := range dependsOn {
+		names = append(names, dependency)
+	}
+	sort.Strings(names) // so that the reported cycle is deterministic
+	for _, dependency := range names {
+		an, ok := Analyzers[dependency]
+		if !ok {
+			continue // reported when adding dependencies
+		}
+		if cycle := findDependencyCycle(dependency, an.DependsOn(), path); len(cycle) > 0 {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// sortTopologically returns the positions of the analyzerWrappers in an order such that every analyzer comes after
+// its dependencies, but otherwise in the same order as given (i.e. filters first). There cannot be cycles because
+// analyzers with dependency cycles are not instantiated.
+func sortTopologically(analyzerWrappers []analyzerWrapper) []int {
+	var (
+		order   = make([]int, 0, len(analyzerWrappers))
+		visited = make([]bool, len(analyzerWrappers))
+		visit   func(pos int)
+	)
+	visit = func(pos int) {
+		if visited[pos] {
+			return
+		}
+		visited[pos] = true
+		dependencyPositions := make([]int, 0, len(analyzerWrappers[pos].dependencies))
+		for _, dependencyPos := range analyzerWrappers[pos].dependencies {
+			dependencyPositions = append(dependencyPositions, dependencyPos)
+		}
+		sort.Ints(dependencyPositions)
+		for _, dependencyPos := range dependencyPositions {
+			visit(dependencyPos)
+		}
+		order = append(order, pos)
+	}
+	for pos := range analyzerWrappers {
+		visit(pos)
+	}
+	return order
+}
+
 func (e Executor) determineRequiredParsingSections() (requiresParsingCommands, requiresParsingMapData bool) {
 	for _, aw := range e.analyzerWrappers {
 		requiresParsingCommands = requiresParsingCommands || aw.analyzer.RequiresParsingCommands()
@@ -360,6 +926,17 @@ func (e Executor) filterReplayPaths(replayPaths []string) (paths []string, errs
 	return
 }
 
+// withoutHiddenResults removes the results of analyzers that were only instantiated as dependencies or -where atoms.
+func (e Executor) withoutHiddenResults(results []Result) []Result {
+	visibleResults := make([]Result, 0, len(results))
+	for i, result := range results {
+		if !e.analyzerWrappers[i].isHidden {
+			visibleResults = append(visibleResults, result)
+		}
+	}
+	return visibleResults
+}
+
 func (e Executor) cloneAnalyzerWrappers() (as []analyzerWrapper) {
 	for _, analyzerWrapper := range e.analyzerWrappers {
 		as = append(as, analyzerWrapper.clone())
@@ -2,11 +2,12 @@ package analyzer
 
 import (
 	"fmt"
+	"math"
 	"path"
-	"reflect"
-	"sort"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/icza/screp/rep"
 	"github.com/icza/screp/rep/repcmd"
@@ -20,22 +21,22 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorRace{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
 				for _, p := range replay.Header.OrigPlayers {
 					if p.Race.Name == args[0] {
-						return "true", true, nil, nil
+						return NewBoolResult(true), true, nil, nil
 					}
 				}
-				return "false", true, nil, nil
+				return NewBoolResult(false), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -43,32 +44,30 @@ var Analyzers = map[string]Analyzer{
 	"my-apm": newAnalyzerImpl(
 		"my-apm",
 		"Analyzes the APM of the -me player.",
-		1, // version
+		2, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeInt, // resultType
 		true,  // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "-1",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
 				if replay.Computed == nil {
-					return "-1", true, nil, nil
+					return NewNullResult(), true, nil, nil
 				}
-				playerID := findPlayerID(replay, ctx.Me)
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "-1", true, nil, fmt.Errorf("-me player not present in this replay")
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
 				}
-				for _, pDesc := range replay.Computed.PlayerDescs {
-					if pDesc.PlayerID == playerID {
-						return fmt.Sprintf("%v", pDesc.APM), true, nil, nil
-					}
+				if pDesc := findPlayerDesc(replay, playerID); pDesc != nil {
+					return NewIntResult(int(pDesc.APM)), true, nil, nil
 				}
-				return "-1", true, nil, fmt.Errorf("unexpected error")
+				return NewNullResult(), true, nil, fmt.Errorf("unexpected error")
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -79,21 +78,21 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				playerID := findPlayerID(replay, ctx.Me)
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "", true, nil, fmt.Errorf("-me player not present in this replay")
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
 				}
-				return replay.Header.PIDPlayers[playerID].Race.Name, true, nil, nil
+				return NewStringResult(replay.Header.PIDPlayers[playerID].Race.Name), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -101,28 +100,78 @@ var Analyzers = map[string]Analyzer{
 	"my-race-is": newAnalyzerImpl(
 		"my-race-is",
 		"Analyzes if the race of the -me player is the one specified.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		2, // version
+		map[string]struct{}{"my-race": struct{}{}}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorRace{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if dependencyResults["my-race"].IsNull() {
+					return NewNullResult(), true, nil, nil // -me player not present in this replay; my-race errors already
+				}
+				return NewBoolResult(dependencyResults["my-race"].String() == args[0]), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-team": newAnalyzerImpl(
+		"my-team",
+		"Analyzes the team number of the -me player.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewIntResult(int(replay.Header.PIDPlayers[playerID].Team)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-start-location": newAnalyzerImpl(
+		"my-start-location",
+		"Analyzes the start location of the -me player, as x,y map tile coordinates.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				playerID := findPlayerID(replay, ctx.Me)
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "", true, nil, fmt.Errorf("-me player not present in this replay")
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
 				}
-				result := "false"
-				if replay.Header.PIDPlayers[playerID].Race.Name == args[0] {
-					result = "true"
+				pDesc := findPlayerDesc(replay, playerID)
+				if pDesc == nil || pDesc.StartLocation == nil {
+					return NewNullResult(), true, nil, nil // e.g. UMS maps may not have start locations
 				}
-				return result, true, nil, nil
+				tile := pixelsToTile(*pDesc.StartLocation)
+				return NewListResult([]Result{NewIntResult(int(tile.X)), NewIntResult(int(tile.Y))}), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -133,17 +182,17 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeTimestamp, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				return replay.Header.StartTime.Format("2006-01-02"), true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewTimestampResult(replay.Header.StartTime, "2006-01-02"), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -154,21 +203,21 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				playerID := findPlayerID(replay, ctx.Me)
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "", true, nil, fmt.Errorf("-me player not present in this replay")
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
 				}
-				return replay.Header.PIDPlayers[playerID].Name, true, nil, nil
+				return NewStringResult(replay.Header.PIDPlayers[playerID].Name), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -179,18 +228,18 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
 				result := path.Base(replayPath)
-				return result[:len(result)-4], true, nil, nil
+				return NewStringResult(result[:len(result)-4]), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -201,48 +250,51 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				return replayPath, true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewStringResult(replayPath), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"my-win": newAnalyzerImpl(
 		"my-win",
-		"Analyzes if the -me player won the game.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		"Analyzes if the -me player won the game, according to screp's winner team. On 1v1s of game types without teams (e.g. Melee), both players are on the same team, so it doesn't tell. Outputs unknown in CSV (null in JSON) when it doesn't tell (see -winners for a heuristic then).",
+		4, // version
+		map[string]struct{}{"is-1v1": struct{}{}}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
 				if replay.Computed == nil || replay.Computed.WinnerTeam == 0 {
-					return "unknown", true, nil, nil
+					return NewNullResult(), true, nil, nil
 				}
-				playerID := findPlayerID(replay, ctx.Me)
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "", true, nil, fmt.Errorf("-me player not present in this replay")
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				if dependencyResults["is-1v1"].IsTrue() && isGameTypeWithoutTeams(replay.Header.Type) {
+					return NewNullResult(), true, nil, nil
 				}
 				if replay.Header.PIDPlayers[playerID].Team == replay.Computed.WinnerTeam {
-					return "true", true, nil, nil
+					return NewBoolResult(true), true, nil, nil
 				}
-				return "false", true, nil, nil
+				return NewBoolResult(false), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -253,20 +305,20 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				if playerID := findPlayerID(replay, ctx.Me); playerID == 127 {
-					return "false", true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if playerID := findPlayerID(replay, ctx); playerID == 127 {
+					return NewBoolResult(false), true, nil, nil
 				}
-				return "true", true, nil, nil
+				return NewBoolResult(true), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -277,71 +329,59 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				return replay.Header.Map, true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewStringResult(replay.Header.Map), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"is-1v1": newAnalyzerImpl(
 		"is-1v1",
-		"Analyzes if the replay is of an 1v1 match.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		"Analyzes if the replay is of an 1v1 match (see -team-format), i.e. between two human players: a player against a computer is not an 1v1.",
+		2, // version
+		map[string]struct{}{"team-format": struct{}{}}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				// TODO: when GameType is Melee every "real" player is in Team 0. This breaks Matchup in screp. Make PR!
-				if len(replay.Header.Players) == 2 && (replay.Header.Players[0].Team != replay.Header.Players[1].Team ||
-					replay.Header.Type.Name == "Melee" ||
-					replay.Header.Type.Name == "One on One" ||
-					replay.Header.Type.Name == "Free For All") {
-					return "true", true, nil, nil
-				}
-				return "false", true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewBoolResult(dependencyResults["team-format"].String() == "1v1"), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"is-2v2": newAnalyzerImpl(
 		"is-2v2",
-		"Analyzes if the replay is of a 2v2 match.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		"Analyzes if the replay is of a 2v2 match (see -team-format), i.e. between two teams of two human players, ignoring computers.",
+		2, // version
+		map[string]struct{}{"team-format": struct{}{}}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				if len(replay.Header.Players) == 4 && replay.Header.Players[0].Team == replay.Header.Players[1].Team &&
-					replay.Header.Players[1].Team != replay.Header.Players[2].Team &&
-					replay.Header.Players[2].Team == replay.Header.Players[3].Team {
-					return "true", true, nil, nil
-				}
-				return "false", true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewBoolResult(dependencyResults["team-format"].String() == "2v2"), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -352,17 +392,17 @@ var Analyzers = map[string]Analyzer{
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeInt, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				return fmt.Sprintf("%v", int(replay.Header.Duration().Minutes())), true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewIntResult(int(replay.Header.Duration().Minutes())), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -370,22 +410,22 @@ var Analyzers = map[string]Analyzer{
 	"duration-minutes-is-greater-than": newAnalyzerImpl(
 		"duration-minutes-is-greater-than",
 		"Analyzes if the duration of the replay in minutes is greater than specified.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		2, // version
+		map[string]struct{}{"duration-minutes": struct{}{}}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorMinutes{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				actualMinutes := int(replay.Header.Duration().Minutes())
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				actualMinutes := dependencyResults["duration-minutes"].Int()
 				expectedMinutes, _ := strconv.Atoi(args[0]) // N.B. Validator already checked it's ok
-				return fmt.Sprintf("%v", actualMinutes > expectedMinutes), true, nil, nil
+				return NewBoolResult(actualMinutes > expectedMinutes), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
@@ -393,173 +433,1669 @@ var Analyzers = map[string]Analyzer{
 	"duration-minutes-is-lower-than": newAnalyzerImpl(
 		"duration-minutes-is-lower-than",
 		"Analyzes if the duration of the replay in minutes is lower than specified.",
-		1, // version
-		map[string]struct{}{}, // dependsOn
+		2, // version
+		map[string]struct{}{"duration-minutes": struct{}{}}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorMinutes{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				actualMinutes := int(replay.Header.Duration().Minutes())
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				actualMinutes := dependencyResults["duration-minutes"].Int()
 				expectedMinutes, _ := strconv.Atoi(args[0]) // N.B. Validator already checked it's ok
-				return fmt.Sprintf("%v", actualMinutes < expectedMinutes), true, nil, nil
+				return NewBoolResult(actualMinutes < expectedMinutes), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"matchup": newAnalyzerImpl(
 		"matchup",
-		"Analyzes the replay's matchup. On an 1v1, it will sort the races lexicographically, so it will return TvZ rather than ZvT. Other than 1v1, it will simply return whatever screp returns.",
-		1, // version
+		"Analyzes the replay's matchup, e.g. TvZ or PT vs ZZ, ignoring observers. Races are sorted lexicographically within teams, and teams are too, so it will return TvZ rather than ZvT.",
+		2, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				if len(replay.Header.Players) == 2 {
-					r0 := strings.ToUpper(string(replay.Header.Players[0].Race.Letter))
-					r1 := strings.ToUpper(string(replay.Header.Players[1].Race.Letter))
-					if r0 > r1 {
-						return r1 + "v" + r0, true, nil, nil
-					}
-					return r0 + "v" + r1, true, nil, nil
-				}
-				return replay.Header.Matchup(), true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewStringResult(formatMatchup(findTeamRaces(replay, 127))), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"my-matchup": newAnalyzerImpl(
 		"my-matchup",
-		"Analyzes the replay's matchup from the point of view of the -me player. For example, if the -me player is Z and the opponent is T it will return ZvT rather than TvZ. At the moment, the behaviour other than 1v1 is unexpected: it returns the matchup as returned by screp.",
-		1, // version
+		"Analyzes the replay's matchup from the point of view of the -me player (see -matchup). For example, if the -me player is Z and the opponent is T it will return ZvT rather than TvZ, and on team games the -me player's team comes first, e.g. PZ vs TT.",
+		2, // version
 		map[string]struct{}{}, // dependsOn
 		false, // isStringFlag
-		false, // isBooleanResult
+		ResultTypeString, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				playerID := findPlayerID(replay, ctx.Me)
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
 				if playerID == 127 {
-					return "", true, nil, nil
-				}
-				if len(replay.Header.Players) == 2 {
-					// N.B. Note that replay.Header.Players is in team order, but team order is irrelevant except on
-					// TvB. Main insight: Players[0].ID is not necessarily == 0.
-					r0 := strings.ToUpper(string(replay.Header.Players[0].Race.Letter))
-					r1 := strings.ToUpper(string(replay.Header.Players[1].Race.Letter))
-					if playerID == replay.Header.Players[1].ID {
-						return r1 + "v" + r0, true, nil, nil
-					}
-					return r0 + "v" + r1, true, nil, nil
+					return NewNullResult(), true, nil, nil
 				}
-				return replay.Header.Matchup(), true, nil, nil // TODO put -me player on the left side
+				return NewStringResult(formatMatchup(findTeamRaces(replay, playerID))), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
 	"matchup-is": newAnalyzerImpl(
 		"matchup-is",
-		"Analyzes if the replay's MatchupIs is equal to the specified one (only works for 1v1 for now). The specified matchup can be in either order (i.e. ZvT == TvZ).",
+		"Analyzes if the replay's matchup (see -matchup) is the specified one, in any team order (i.e. ZvT == TvZ). Use ? for any race, e.g. Z? v ?? is 2v2s with a Zerg.",
+		3, // version
+		map[string]struct{}{"matchup": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorMatchup{},
+		&analyzerProcessorImpl{
+			result: NewBoolResult(false),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				teams, _ := parseMatchup(dependencyResults["matchup"].String())
+				return NewBoolResult(matchupMatches(teams, args, false)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-matchup-is": newAnalyzerImpl(
+		"my-matchup-is",
+		"Analyzes if the replay's matchup is the specified one, from the -me player perspective (see -my-matchup). The specified matchup must contain the -me player's team first, but other teams can be in any order. Use ? for any race, e.g. Z? v ?? is 2v2s with a Zerg on the -me player's team.",
+		3, // version
+		map[string]struct{}{"my-matchup": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorMatchup{},
+		&analyzerProcessorImpl{
+			result: NewBoolResult(false),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if dependencyResults["my-matchup"].IsNull() {
+					return NewNullResult(), true, nil, nil
+				}
+				teams, _ := parseMatchup(dependencyResults["my-matchup"].String())
+				return NewBoolResult(matchupMatches(teams, args, true)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-first-specific-unit-seconds": newAnalyzerImpl(
+		"my-first-specific-unit-seconds",
+		"Analyzes the time the first specified unit/building/evolution was built, in seconds. Refer to the unit name list in utils.go#nameToUnitID. -1 in CSV (null in JSON) if the unit never appears.",
+		2, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorUnit{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				unitID, _ := strconv.Atoi(args[0]) // N.B. Validator already checked it's ok
+				playerID := findPlayerID(replay, ctx)
+				state := []int{unitID, int(playerID)}
+				return NewNullResult(), playerID == 127, state, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				_state := state.([]int)
+				unitID, playerID := _state[0], _state[1]
+				seconds, done := maybePlayersUnitSeconds(command, byte(playerID), uint16(unitID))
+				if !done {
+					return result, false, nil
+				}
+				return NewIntResult(seconds), true, nil
+			},
+		},
+	),
+	"opponent-name": newAnalyzerImpl(
+		"opponent-name",
+		"Analyzes the name of the -me player's opponent. Empty unless there's a single opponent (e.g. 1v1s); see -opponent-names.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				opponentID := findOpponentID(replay, ctx)
+				if opponentID == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(replay.Header.PIDPlayers[opponentID].Name), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-names": newAnalyzerImpl(
+		"opponent-names",
+		"Analyzes the names of the -me player's opponents, in team order.",
 		1, // version
 		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if findPlayerID(replay, ctx) == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				names := []Result{}
+				for _, opponentID := range findOpponentIDs(replay, ctx) {
+					names = append(names, NewStringResult(replay.Header.PIDPlayers[opponentID].Name))
+				}
+				return NewListResult(names), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-name-is": newAnalyzerImpl(
+		"opponent-name-is",
+		"Analyzes if any of the -me player's opponents is one of the specified (comma-separated) player names.",
+		1, // version
+		map[string]struct{}{"opponent-names": struct{}{}}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
-		&argumentValidator1v1Matchup{},
+		&argumentValidatorPlayerNames{},
 		&analyzerProcessorImpl{
-			result: "false",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				if len(replay.Header.Players) != 2 {
-					return "", true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if dependencyResults["opponent-names"].IsNull() {
+					return NewNullResult(), true, nil, nil // -me player not present in this replay
 				}
-				actualRaces := []string{
-					strings.ToUpper(string(replay.Header.Players[0].Race.Letter)),
-					strings.ToUpper(string(replay.Header.Players[1].Race.Letter)),
+				for _, name := range dependencyResults["opponent-names"].List() {
+					for _, arg := range args {
+						if name.String() == arg {
+							return NewBoolResult(true), true, nil, nil
+						}
+					}
 				}
-				sort.Strings(actualRaces)
-				return fmt.Sprintf("%v", reflect.DeepEqual(args, actualRaces)), true, nil, nil
+				return NewBoolResult(false), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
-	"my-matchup-is": newAnalyzerImpl(
-		"my-matchup-is",
-		"Analyzes if the replay's matchup is equal to the specified one, from the -me player perspective (only works for 1v1 for now). The specified matchup must contain the -me player's race first.",
+	"opponent-race": newAnalyzerImpl(
+		"opponent-race",
+		"Analyzes the race of the -me player's opponent. Empty unless there's a single opponent (e.g. 1v1s); see -opponent-races.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				opponentID := findOpponentID(replay, ctx)
+				if opponentID == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(replay.Header.PIDPlayers[opponentID].Race.Name), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-races": newAnalyzerImpl(
+		"opponent-races",
+		"Analyzes the races of the -me player's opponents, in team order.",
 		1, // version
 		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if findPlayerID(replay, ctx) == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				races := []Result{}
+				for _, opponentID := range findOpponentIDs(replay, ctx) {
+					races = append(races, NewStringResult(replay.Header.PIDPlayers[opponentID].Race.Name))
+				}
+				return NewListResult(races), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-race-is": newAnalyzerImpl(
+		"opponent-race-is",
+		"Analyzes if the race of the -me player's opponent is the one specified. Empty unless there's a single opponent (e.g. 1v1s).",
+		1, // version
+		map[string]struct{}{"opponent-race": struct{}{}}, // dependsOn
 		true,  // isStringFlag
-		true,  // isBooleanResult
+		ResultTypeBool, // resultType
 		false, // requiresParsingCommands
 		false, // requiresParsingMapData
-		&argumentValidator1v1Matchup{},
+		&argumentValidatorRace{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if dependencyResults["opponent-race"].IsNull() {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewBoolResult(dependencyResults["opponent-race"].String() == args[0]), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-apm": newAnalyzerImpl(
+		"opponent-apm",
+		"Analyzes the APM of the -me player's opponent. Empty unless there's a single opponent (e.g. 1v1s).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
 		&analyzerProcessorImpl{
-			result: "false",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
-				playerID := findPlayerID(replay, ctx.Me)
-				if playerID == 127 || len(replay.Header.Players) != 2 {
-					return "", true, nil, nil
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				opponentID := findOpponentID(replay, ctx)
+				if opponentID == 127 {
+					return NewNullResult(), true, nil, nil
 				}
-				actualRaces := []string{
-					strings.ToUpper(string(replay.Header.Players[0].Race.Letter)),
-					strings.ToUpper(string(replay.Header.Players[1].Race.Letter)),
+				if pDesc := findPlayerDesc(replay, opponentID); pDesc != nil {
+					return NewIntResult(int(pDesc.APM)), true, nil, nil
 				}
-				sort.Strings(actualRaces)
-				return fmt.Sprintf("%v", reflect.DeepEqual(args, actualRaces)), true, nil, nil
+				return NewNullResult(), true, nil, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				return result, true, nil
 			},
 		},
 	),
-	"my-first-specific-unit-seconds": newAnalyzerImpl(
-		"my-first-specific-unit-seconds",
-		"Analyzes the time the first specified unit/building/evolution was built, in seconds. Refer to the unit name list in utils.go#nameToUnitID. -1 if the unit never appears.",
+	"opponent-first-specific-unit-seconds": newAnalyzerImpl(
+		"opponent-first-specific-unit-seconds",
+		"Analyzes the time the -me player's opponent first built the specified unit/building/evolution, in seconds. Refer to the unit name list in utils.go#nameToUnitID. Empty if the unit never appears, or unless there's a single opponent (e.g. 1v1s).",
 		1, // version
 		map[string]struct{}{}, // dependsOn
 		true,  // isStringFlag
-		false, // isBooleanResult
+		ResultTypeInt, // resultType
 		true,  // requiresParsingCommands
 		false, // requiresParsingMapData
 		&argumentValidatorUnit{},
 		&analyzerProcessorImpl{
-			result: "-1",
+			result: NewNullResult(),
 			done:   false,
-			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string) (string, bool, interface{}, error) {
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
 				unitID, _ := strconv.Atoi(args[0]) // N.B. Validator already checked it's ok
-				playerID := findPlayerID(replay, ctx.Me)
-				state := []int{unitID, int(playerID)}
-				return "-1", playerID == 127, state, nil
+				opponentID := findOpponentID(replay, ctx)
+				state := []int{unitID, int(opponentID)}
+				return NewNullResult(), opponentID == 127, state, nil
 			},
-			processCommand: func(command repcmd.Cmd, args []string, result string, state interface{}) (string, bool, error) {
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
 				_state := state.([]int)
-				unitID, playerID := _state[0], _state[1]
-				result, done := maybePlayersUnitSeconds(command, byte(playerID), uint16(unitID))
-				return result, done, nil
+				unitID, opponentID := _state[0], _state[1]
+				seconds, done := maybePlayersUnitSeconds(command, byte(opponentID), uint16(unitID))
+				if !done {
+					return result, false, nil
+				}
+				return NewIntResult(seconds), true, nil
+			},
+		},
+	),
+	"my-build-order": newAnalyzerImpl(
+		"my-build-order",
+		"Analyzes the build order of the -me player: the first N (default 20) buildings, units, techs and upgrades, with the time they were ordered.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorOptionalCount{defaultCount: 20},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				count, _ := strconv.Atoi(args[0]) // N.B. Validator already checked it's ok
+				return NewListResult([]Result{}), false, newBuildOrder(playerID, count), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				buildOrder := state.(*buildOrder)
+				if !buildOrder.add(command) {
+					return result, false, nil
+				}
+				return NewListResult(buildOrder.results()), buildOrder.isComplete(), nil
+			},
+		},
+	),
+	"my-opening": newAnalyzerImpl(
+		"my-opening",
+		"Analyzes the opening of the -me player, e.g. 9 pool, BBS or 1 gate core, according to the rules in the -openings file (or the default ones). Empty if no rule matches.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				openings := ctx.Openings
+				if openings == nil {
+					openings = DefaultOpenings
+				}
+				state := &openingState{openings, replay.Header.PIDPlayers[playerID].Race.Name, newBuildOrder(playerID, 0)}
+				return state.result(), false, state, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				_state := state.(*openingState)
+				if command.BaseCmd().Frame.Duration() >= openingHorizon {
+					return result, true, nil
+				}
+				if !_state.buildOrder.add(command) {
+					return result, false, nil
+				}
+				return _state.result(), false, nil
+			},
+		},
+	),
+	"my-actions": newAnalyzerImpl(
+		"my-actions",
+		"Analyzes the actions of the -me player: the number of actions and of effective ones, the EAPM, the redundancy, and the number of actions and of effective ones in each minute (see -my-eapm, -my-redundancy, -my-apm-per-minute and -my-eapm-per-minute).",
+		2, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeRecord, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewNullResult(), false, newEAPMCounter(playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				counter := state.(*eapmCounter)
+				if command.BaseCmd().PlayerID != counter.playerID {
+					return result, false, nil
+				}
+				counter.add(command)
+				return counter.result(), false, nil
+			},
+		},
+	),
+	"my-eapm": newAnalyzerImpl(
+		"my-eapm",
+		"Analyzes the EAPM (Effective APM) of the -me player, i.e. APM without spam: fast reselections, repeated hotkey assigns, fast repeated orders and fast cancels.",
+		2, // version
+		map[string]struct{}{"my-actions": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return recordField(dependencyResults["my-actions"], "eapm"), true, nil, nil // N.B. null if my-actions is
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-redundancy": newAnalyzerImpl(
+		"my-redundancy",
+		"Analyzes the ratio of ineffective actions (see -my-eapm) of the -me player, from 0 to 1.",
+		2, // version
+		map[string]struct{}{"my-actions": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeFloat, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return recordField(dependencyResults["my-actions"], "redundancy"), true, nil, nil // N.B. null if my-actions is
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-apm-per-minute": newAnalyzerImpl(
+		"my-apm-per-minute",
+		"Analyzes the APM of the -me player in each minute of the game, i.e. the number of actions in each minute (the last one may be partial).",
+		2, // version
+		map[string]struct{}{"my-actions": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return recordField(dependencyResults["my-actions"], "apm-per-minute"), true, nil, nil // N.B. null if my-actions is
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-eapm-per-minute": newAnalyzerImpl(
+		"my-eapm-per-minute",
+		"Analyzes the EAPM (see -my-eapm) of the -me player in each minute of the game, i.e. the number of effective actions in each minute (the last one may be partial).",
+		2, // version
+		map[string]struct{}{"my-actions": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return recordField(dependencyResults["my-actions"], "eapm-per-minute"), true, nil, nil // N.B. null if my-actions is
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-peak-apm": newAnalyzerImpl(
+		"my-peak-apm",
+		"Analyzes the highest APM of the -me player in a minute of the game (see -my-apm-per-minute).",
+		1, // version
+		map[string]struct{}{"my-apm-per-minute": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				perMinute := dependencyResults["my-apm-per-minute"]
+				if perMinute.IsNull() {
+					return NewNullResult(), true, nil, nil
+				}
+				peak := 0
+				for _, apm := range perMinute.List() {
+					if apm.Int() > peak {
+						peak = apm.Int()
+					}
+				}
+				return NewIntResult(peak), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-apm-at-minute": newAnalyzerImpl(
+		"my-apm-at-minute",
+		"Analyzes the APM of the -me player in the specified minute of the game, e.g. 10 is from 10:00 to 10:59 (see -my-apm-per-minute). Empty if the player had left.",
+		1, // version
+		map[string]struct{}{"my-apm-per-minute": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorMinutes{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				perMinute := dependencyResults["my-apm-per-minute"]
+				minute, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorMinutes
+				if perMinute.IsNull() || minute < 0 || minute >= len(perMinute.List()) {
+					return NewNullResult(), true, nil, nil
+				}
+				return perMinute.List()[minute], true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-expansion-seconds": newAnalyzerImpl(
+		"my-expansion-seconds",
+		"Analyzes the time the -me player took the Nth expansion (default 1st, i.e. usually the natural), in seconds. Macro hatcheries and the like, i.e. town halls away from mineral fields or near the player's other bases, are not expansions. Empty if the player didn't take that many expansions.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorOptionalCount{defaultCount: 1},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewNullResult(), false, newExpansionTracker(replay, playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				tracker := state.(*expansionTracker)
+				tracker.add(command)
+				n, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorOptionalCount
+				if len(tracker.expansionFrames) < n {
+					return result, false, nil
+				}
+				return NewIntResult(int(tracker.expansionFrames[n-1].Seconds())), true, nil
+			},
+		},
+	),
+	"my-expansions": newAnalyzerImpl(
+		"my-expansions",
+		"Analyzes the number of expansions the -me player took (see -my-expansion-seconds).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewIntResult(0), false, newExpansionTracker(replay, playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				tracker := state.(*expansionTracker)
+				tracker.add(command)
+				return NewIntResult(len(tracker.expansionFrames)), false, nil
+			},
+		},
+	),
+	"my-town-halls": newAnalyzerImpl(
+		"my-town-halls",
+		"Analyzes the number of town halls (i.e. Command Centers, Nexuses and Hatcheries) the -me player built, including macro hatcheries and excluding the starting one.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewIntResult(0), false, newExpansionTracker(replay, playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				tracker := state.(*expansionTracker)
+				tracker.add(command)
+				return NewIntResult(tracker.townHalls), false, nil
+			},
+		},
+	),
+	"my-expanded-before-first-gas": newAnalyzerImpl(
+		"my-expanded-before-first-gas",
+		"Analyzes if the -me player took an expansion (see -my-expansion-seconds) before taking gas.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewBoolResult(false), false, newExpansionTracker(replay, playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				tracker := state.(*expansionTracker)
+				tracker.add(command)
+				expanded, isKnown := tracker.expandedBeforeFirstGas()
+				return NewBoolResult(expanded), isKnown, nil
+			},
+		},
+	),
+	"my-proxy": newAnalyzerImpl(
+		"my-proxy",
+		"Analyzes if the -me player proxied in the first 5 minutes, e.g. proxy gates/barracks, cannon rushes and bunker rushes: built away from their start location (optionally specify the minimum distance in map tiles; default 30) and closer to an opponent's.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorOptionalDistance{defaultDistance: defaultProxyDistance},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				minDistance, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorOptionalDistance
+				return NewBoolResult(false), false, newProxyDetector(replay, []byte{playerID}, minDistance), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				detector := state.(*proxyDetector)
+				if detector.isPastHorizon(command) {
+					return result, true, nil
+				}
+				if detector.isProxy(command) {
+					return NewBoolResult(true), true, nil
+				}
+				return result, false, nil
+			},
+		},
+	),
+	"opponent-proxy": newAnalyzerImpl(
+		"opponent-proxy",
+		"Analyzes if any of the -me player's opponents proxied (see -my-proxy; optionally specify the minimum distance in map tiles; default 30).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorOptionalDistance{defaultDistance: defaultProxyDistance},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				minDistance, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorOptionalDistance
+				return NewBoolResult(false), false, newProxyDetector(replay, findOpponentIDs(replay, ctx), minDistance), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				detector := state.(*proxyDetector)
+				if detector.isPastHorizon(command) {
+					return result, true, nil
+				}
+				if detector.isProxy(command) {
+					return NewBoolResult(true), true, nil
+				}
+				return result, false, nil
+			},
+		},
+	),
+	"proxy-detected": newAnalyzerImpl(
+		"proxy-detected",
+		"Analyzes if any player proxied (see -my-proxy; optionally specify the minimum distance in map tiles; default 30).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorOptionalDistance{defaultDistance: defaultProxyDistance},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				minDistance, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorOptionalDistance
+				return NewBoolResult(false), false, newProxyDetector(replay, findPlayingPlayerIDs(replay), minDistance), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				detector := state.(*proxyDetector)
+				if detector.isPastHorizon(command) {
+					return result, true, nil
+				}
+				if detector.isProxy(command) {
+					return NewBoolResult(true), true, nil
+				}
+				return result, false, nil
+			},
+		},
+	),
+	"my-first-tech-seconds": newAnalyzerImpl(
+		"my-first-tech-seconds",
+		"Analyzes the time the -me player first researched the specified tech (e.g. Stim Packs, Lurker Aspect), in seconds. Empty if never.",
+		2, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorTech{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewNullResult(), false, playerID, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				c, ok := command.(*repcmd.TechCmd)
+				if !ok || c.PlayerID != state.(byte) || c.Tech.Name != args[0] {
+					return result, false, nil
+				}
+				return NewIntResult(int(c.Frame.Seconds())), true, nil
+			},
+		},
+	),
+	"my-first-upgrade-seconds": newAnalyzerImpl(
+		"my-first-upgrade-seconds",
+		"Analyzes the time the -me player first started the specified upgrade (e.g. Metabolic Boost, Protoss Ground Weapons), in seconds. Empty if never.",
+		2, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorUpgrade{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewNullResult(), false, playerID, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				c, ok := command.(*repcmd.UpgradeCmd)
+				if !ok || c.PlayerID != state.(byte) || c.Upgrade.Name != args[0] {
+					return result, false, nil
+				}
+				return NewIntResult(int(c.Frame.Seconds())), true, nil
+			},
+		},
+	),
+	"my-upgrades": newAnalyzerImpl(
+		"my-upgrades",
+		"Analyzes the upgrades the -me player started, in order. Upgrades with levels appear once per level.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewListResult([]Result{}), false, newUpgradeList(playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				upgrades := state.(*upgradeList)
+				if !upgrades.add(command) {
+					return result, false, nil
+				}
+				return NewListResult(upgrades.results()), false, nil
+			},
+		},
+	),
+	"my-unit-count": newAnalyzerImpl(
+		"my-unit-count",
+		"Analyzes how many of the specified unit/building the -me player ordered, optionally before the specified minute (e.g. 'Mutalisk,10'). Refer to the unit name list in utils.go#nameToUnitID. Zerglings and Scourges count twice, since each larva morphs into two.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorUnitAndOptionalMinutes{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewIntResult(0), false, newBuildOrder(playerID, 0), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				unitID, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorUnitAndOptionalMinutes
+				if len(args) > 1 {
+					minutes, _ := strconv.Atoi(args[1])
+					if command.BaseCmd().Frame.Duration() >= time.Duration(minutes)*time.Minute {
+						return result, true, nil
+					}
+				}
+				b := state.(*buildOrder)
+				if !b.add(command) {
+					return result, false, nil
+				}
+				return NewIntResult(b.unitCount(uint16(unitID))), false, nil
+			},
+		},
+	),
+	"my-unit-composition": newAnalyzerImpl(
+		"my-unit-composition",
+		"Analyzes how many of each unit the -me player ordered, the most numerous first. Zerglings and Scourges count twice, since each larva morphs into two.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewListResult([]Result{}), false, newBuildOrder(playerID, 0), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				b := state.(*buildOrder)
+				if !b.add(command) {
+					return result, false, nil
+				}
+				return NewListResult(b.unitComposition()), false, nil
+			},
+		},
+	),
+	"my-workers-per-minute": newAnalyzerImpl(
+		"my-workers-per-minute",
+		"Analyzes how many workers (i.e. SCVs, Probes and Drones) the -me player ordered in each minute of the game, until the last one. SCV and Probe orders onto full production queues are ignored.",
+		3, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewListResult([]Result{}), false, newWorkerTimeline(playerID), nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				workers := state.(*workerTimeline)
+				if !workers.add(command) {
+					return result, false, nil
+				}
+				return NewListResult(workers.perMinute()), false, nil
+			},
+		},
+	),
+	"my-workers-produced-per-minute": newAnalyzerImpl(
+		"my-workers-produced-per-minute",
+		"Analyzes an estimate of how many workers the -me player produced in each minute of the game, until the last one, i.e. when the ones ordered (see -my-workers-per-minute) finished training, after the ones queued before them. Workers that wouldn't finish before the end of the game are not counted.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				return NewListResult([]Result{}), false, []interface{}{newWorkerTimeline(playerID), replay.Header.Duration()}, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				_state := state.([]interface{})
+				workers, end := _state[0].(*workerTimeline), _state[1].(time.Duration)
+				if !workers.add(command) {
+					return result, false, nil
+				}
+				return NewListResult(workers.producedPerMinute(end)), false, nil
+			},
+		},
+	),
+	"my-worker-count-at-minute": newAnalyzerImpl(
+		"my-worker-count-at-minute",
+		"Analyzes an estimate of how many workers the -me player had at the specified minute, e.g. 5 is at 5:00: the starting ones plus the ones produced until then (see -my-workers-produced-per-minute), regardless of losses. Empty if the game was shorter.",
+		3, // version
+		map[string]struct{}{"my-workers-produced-per-minute": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorMinutes{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				perMinute := dependencyResults["my-workers-produced-per-minute"]
+				minute, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorMinutes
+				if perMinute.IsNull() || minute < 0 || replay.Header.Duration() < time.Duration(minute)*time.Minute {
+					return NewNullResult(), true, nil, nil
+				}
+				count := startingWorkers
+				for i, workers := range perMinute.List() {
+					if i < minute {
+						count += workers.Int()
+					}
+				}
+				return NewIntResult(count), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-longest-worker-gap-seconds": newAnalyzerImpl(
+		"my-longest-worker-gap-seconds",
+		"Analyzes the longest time in seconds the -me player didn't order workers, from the beginning of the game until the specified minute (default 10) or the end of the game.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorOptionalMinutes{defaultMinutes: 10},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				minutes, _ := strconv.Atoi(args[0]) // N.B. validated by argumentValidatorOptionalMinutes
+				until := time.Duration(minutes) * time.Minute
+				if replay.Header.Duration() < until {
+					until = replay.Header.Duration()
+				}
+				workers := newWorkerTimeline(playerID)
+				return NewIntResult(int(workers.longestGap(until).Seconds())), false, []interface{}{workers, until}, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				_state := state.([]interface{})
+				workers, until := _state[0].(*workerTimeline), _state[1].(time.Duration)
+				if command.BaseCmd().Frame.Duration() > until {
+					return result, true, nil
+				}
+				if !workers.add(command) {
+					return result, false, nil
+				}
+				return NewIntResult(int(workers.longestGap(until).Seconds())), false, nil
+			},
+		},
+	),
+	"team-format": newAnalyzerImpl(
+		"team-format",
+		"Analyzes the team format of the replay, e.g. 1v1, 2v2, 3v1, 2v2v2v2 or FFA4 (teams from largest to smallest), ignoring observers and computers.",
+		3, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				format, ok := teamFormat(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(format), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"team-format-is": newAnalyzerImpl(
+		"team-format-is",
+		"Analyzes if the team format of the replay (see -team-format) is one of the specified (comma-separated) ones, e.g. 2v2,3v3.",
+		1, // version
+		map[string]struct{}{"team-format": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorTeamFormats{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if dependencyResults["team-format"].IsNull() {
+					return NewNullResult(), true, nil, nil
+				}
+				for _, format := range args {
+					if dependencyResults["team-format"].String() == format {
+						return NewBoolResult(true), true, nil, nil
+					}
+				}
+				return NewBoolResult(false), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-start-clock": newAnalyzerImpl(
+		"my-start-clock",
+		"Analyzes the start location of the -me player as a clock position relative to the center of the map, e.g. 12 is top and 3 is right.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				pDesc := findPlayerDesc(replay, playerID)
+				if pDesc == nil || pDesc.StartLocation == nil {
+					return NewNullResult(), true, nil, nil // e.g. UMS maps may not have start locations
+				}
+				return NewIntResult(int(pDesc.StartDirection)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"opponent-start-clock": newAnalyzerImpl(
+		"opponent-start-clock",
+		"Analyzes the start location of the -me player's opponent as a clock position (see -my-start-clock). Empty unless there's a single opponent (e.g. 1v1s).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findOpponentID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, nil
+				}
+				pDesc := findPlayerDesc(replay, playerID)
+				if pDesc == nil || pDesc.StartLocation == nil {
+					return NewNullResult(), true, nil, nil // e.g. UMS maps may not have start locations
+				}
+				return NewIntResult(int(pDesc.StartDirection)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"spawn-distance": newAnalyzerImpl(
+		"spawn-distance",
+		"Analyzes the distance between the start locations of the players of an 1v1, in map tiles (as the crow flies). Empty on other games.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				a, b, ok := find1v1StartLocationTiles(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewIntResult(int(math.Round(tileDistance(a, b)))), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"spawn-type": newAnalyzerImpl(
+		"spawn-type",
+		"Analyzes the relative position of the start locations of the players of an 1v1: cross (opposite corners), vertical, horizontal or close-by-air (less than half the map apart both ways). Empty on other games.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				a, b, ok := find1v1StartLocationTiles(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(spawnType(a, b, replay.Header.MapWidth, replay.Header.MapHeight)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"map-fingerprint": newAnalyzerImpl(
+		"map-fingerprint",
+		"Analyzes a hash of the map's terrain and resources (dimensions, tileset, tiles, mineral fields, geysers and start locations), which tells apart map versions regardless of their names.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				fingerprint, ok := mapFingerprint(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(fingerprint), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"canonical-map": newAnalyzerImpl(
+		"canonical-map",
+		"Analyzes the map's name in the -maps catalog of known maps, by its fingerprint (see -map-fingerprint) or otherwise its normalized name (see -map-name-normalized). Empty if it's not in the catalog.",
+		2, // version
+		map[string]struct{}{"map-fingerprint": struct{}{}}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		true,  // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				name, ok := findCanonicalMap(ctx.Maps, mapNameRules(ctx), dependencyResults["map-fingerprint"].String(), replay.Header.Map)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(name), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"map-name-normalized": newAnalyzerImpl(
+		"map-name-normalized",
+		"Analyzes the map's name without color codes, tournament prefixes and version numbers (according to the rules in the -map-name-rules file, or the default ones), e.g. | iCCup | Fighting Spirit 1.3 is Fighting Spirit.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				return NewStringResult(normalizeMapName(replay.Header.Map, mapNameRules(ctx))), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"map-name-is": newAnalyzerImpl(
+		"map-name-is",
+		"Analyzes if the map's normalized name (see -map-name-normalized) is one of the specified (comma-separated) map names, ignoring case, e.g. Fighting Spirit,Circuit Breaker.",
+		1, // version
+		map[string]struct{}{"map-name-normalized": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorMapNames{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				for _, arg := range args {
+					if strings.EqualFold(normalizeMapName(arg, mapNameRules(ctx)), dependencyResults["map-name-normalized"].String()) {
+						return NewBoolResult(true), true, nil, nil
+					}
+				}
+				return NewBoolResult(false), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"map-name-matches": newAnalyzerImpl(
+		"map-name-matches",
+		"Analyzes if the map's normalized name (see -map-name-normalized) matches the specified regular expression, ignoring case, e.g. ^(fighting spirit|circuit breakers?)$.",
+		1, // version
+		map[string]struct{}{"map-name-normalized": struct{}{}}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		false, // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorRegexp{},
+		&analyzerProcessorRegexp{
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, r *regexp.Regexp, dependencyResults map[string]Result) (Result, error) {
+				return NewBoolResult(r.MatchString(dependencyResults["map-name-normalized"].String())), nil
+			},
+		},
+	),
+	"chat": newAnalyzerImpl(
+		"chat",
+		"Analyzes the in-game chat, as a list of time, sender name and message of each chat message.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewListResult(chatLog(replay)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"chat-contains": newAnalyzerImpl(
+		"chat-contains",
+		"Analyzes if any in-game chat message contains the specified text, as a regular expression ignoring case, e.g. gg or ^(gg|ggwp)$.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		true,  // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorRegexp{},
+		&analyzerProcessorRegexp{
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, r *regexp.Regexp, dependencyResults map[string]Result) (Result, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), nil
+				}
+				return NewBoolResult(chatContains(replay, r)), nil
+			},
+		},
+	),
+	"said-gg": newAnalyzerImpl(
+		"said-gg",
+		"Analyzes who said \"good game\" first in the in-game chat (e.g. gg, GG wp or good game). Empty if nobody did.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				name, ok := findFirstGGSender(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(name), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"chat-message-count": newAnalyzerImpl(
+		"chat-message-count",
+		"Analyzes the number of in-game chat messages.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewIntResult(len(replay.Computed.ChatCmds)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"leaves": newAnalyzerImpl(
+		"leaves",
+		"Analyzes the players who left the game, as a list of name, time, frame and reason (e.g. Defeat) of each leave, in order. The player who saved the replay usually doesn't leave.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewListResult(leavesResults(replay, findLeaves(replay))), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"first-leaver": newAnalyzerImpl(
+		"first-leaver",
+		"Analyzes the name of the first player who left the game. Empty if nobody left.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				leaves := findLeaves(replay)
+				if len(leaves) == 0 {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(replay.Header.PIDPlayers[leaves[0].PlayerID].Name), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-leave-frame": newAnalyzerImpl(
+		"my-leave-frame",
+		"Analyzes the frame at which the -me player left the game. Empty if the player didn't leave (e.g. usually the player who saved the replay).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeInt, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				c := findLeave(replay, playerID)
+				if c == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewIntResult(int(c.Frame)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-leave-reason": newAnalyzerImpl(
+		"my-leave-reason",
+		"Analyzes the reason why the -me player left the game, e.g. Defeat, Victory, Quit or Dropped. Empty if the player didn't leave (e.g. usually the player who saved the replay).",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				c := findLeave(replay, playerID)
+				if c == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(leaveReasonName(c)), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"winners": newAnalyzerImpl(
+		"winners",
+		"Analyzes the names of the players who won the game, by screp's winner team or otherwise, on games between two teams, by leave reasons, leave order, who said gg first and last commands (see -winner-confidence). Empty if unknown.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeList, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				winners, _, ok := findWinners(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				names := make([]Result, len(winners))
+				for i, winnerID := range winners {
+					names[i] = NewStringResult(replay.Header.PIDPlayers[winnerID].Name)
+				}
+				return NewListResult(names), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"winner-confidence": newAnalyzerImpl(
+		"winner-confidence",
+		"Analyzes which signal decided the winners of the game (see -winners), from most to least reliable: team (screp's winner team), leave-reason (someone left with Defeat or Victory), leave-order (the first to leave loses), gg (the first to say gg loses) or last-command (the last to give commands wins). Empty if unknown.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeString, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				if replay.Computed == nil {
+					return NewNullResult(), true, nil, nil
+				}
+				_, signal, ok := findWinners(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				return NewStringResult(signal), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
+			},
+		},
+	),
+	"my-inferred-win": newAnalyzerImpl(
+		"my-inferred-win",
+		"Analyzes if the -me player won the game, according to the heuristic of -winners, which also decides most games where screp doesn't know the winner team (see -winner-confidence). Empty if unknown.",
+		1, // version
+		map[string]struct{}{}, // dependsOn
+		false, // isStringFlag
+		ResultTypeBool, // resultType
+		true,  // requiresParsingCommands
+		false, // requiresParsingMapData
+		&argumentValidatorNoArguments{},
+		&analyzerProcessorImpl{
+			result: NewNullResult(),
+			done:   false,
+			startReadingReplay: func(replay *rep.Replay, ctx Context, replayPath string, args []string, dependencyResults map[string]Result) (Result, bool, interface{}, error) {
+				playerID := findPlayerID(replay, ctx)
+				if playerID == 127 {
+					return NewNullResult(), true, nil, fmt.Errorf("-me player not present in this replay")
+				}
+				winners, _, ok := findWinners(replay)
+				if !ok {
+					return NewNullResult(), true, nil, nil
+				}
+				for _, winnerID := range winners {
+					if winnerID == playerID {
+						return NewBoolResult(true), true, nil, nil
+					}
+				}
+				return NewBoolResult(false), true, nil, nil
+			},
+			processCommand: func(command repcmd.Cmd, args []string, result Result, state interface{}) (Result, bool, error) {
+				return result, true, nil
 			},
 		},
 	),
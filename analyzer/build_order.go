@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// buildOrder accumulates the build order of a player from the commands of a replay, i.e. the buildings, units, techs
+// and upgrades the player ordered. Repeated BuildCmds of the same building on the same position (e.g. while the
+// worker walks there, or if it can't be placed yet) are only counted once.
+type buildOrder struct {
+	playerID byte
+	count    int // of items to accumulate; 0 for no limit
+	items    []buildOrderItem
+	placed   map[buildingPlacement]struct{}
+}
+
+type buildOrderItem struct {
+	frame  repcore.Frame
+	kind   string // building, unit, tech or upgrade
+	name   string
+	unitID uint16 // of buildings and units
+}
+
+type buildingPlacement struct {
+	unitID uint16
+	pos    repcore.Point
+}
+
+func newBuildOrder(playerID byte, count int) *buildOrder {
+	return &buildOrder{playerID: playerID, count: count, placed: map[buildingPlacement]struct{}{}}
+}
+
+// add adds the command to the build order if it's a build order item of the player. Returns true if it was.
+func (b *buildOrder) add(command repcmd.Cmd) bool {
+	if command.BaseCmd().PlayerID != b.playerID || b.isComplete() {
+		return false
+	}
+	item := buildOrderItem{frame: command.BaseCmd().Frame}
+	switch c := command.(type) {
+	case *repcmd.BuildCmd:
+		if c.Order.Name == "BuildingLand" { // N.B. landing a lifted Terran building is not building it
+			return false
+		}
+		placement := buildingPlacement{c.Unit.ID, c.Pos}
+		if _, ok := b.placed[placement]; ok {
+			return false
+		}
+		b.placed[placement] = struct{}{}
+		item.kind, item.name, item.unitID = "building", c.Unit.Name, c.Unit.ID
+	case *repcmd.BuildingMorphCmd:
+		item.kind, item.name, item.unitID = "building", c.Unit.Name, c.Unit.ID
+	case *repcmd.TrainCmd: // N.B. includes Zerg larva morphs
+		item.kind, item.name, item.unitID = "unit", c.Unit.Name, c.Unit.ID
+	case *repcmd.TechCmd:
+		item.kind, item.name = "tech", c.Tech.Name
+	case *repcmd.UpgradeCmd:
+		item.kind, item.name = "upgrade", c.Upgrade.Name
+	default:
+		return false
+	}
+	b.items = append(b.items, item)
+	return true
+}
+
+// isComplete returns true if count items have been accumulated.
+func (b *buildOrder) isComplete() bool {
+	return b.count > 0 && len(b.items) >= b.count
+}
+
+// unitCount returns how many of the given unit or building were ordered. Zerglings and Scourges count twice, since
+// each larva morphs into two.
+func (b *buildOrder) unitCount(unitID uint16) int {
+	count := 0
+	for _, item := range b.items {
+		if item.kind != "tech" && item.kind != "upgrade" && item.unitID == unitID {
+			count += unitsPerOrder(item)
+		}
+	}
+	return count
+}
+
+// unitComposition returns the units (not buildings) that were ordered as a list of records with the name and count
+// of each unit, the most numerous first. Zerglings and Scourges count twice (see unitCount).
+func (b *buildOrder) unitComposition() []Result {
+	var (
+		counts = map[string]int{}
+		names  = []string{}
+	)
+	for _, item := range b.items {
+		if item.kind != "unit" {
+			continue
+		}
+		if _, ok := counts[item.name]; !ok {
+			names = append(names, item.name)
+		}
+		counts[item.name] += unitsPerOrder(item)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	results := make([]Result, len(names))
+	for i, name := range names {
+		results[i] = NewRecordResult([]RecordField{
+			{Name: "unit", Value: NewStringResult(name)},
+			{Name: "count", Value: NewIntResult(counts[name])},
+		})
+	}
+	return results
+}
+
+func unitsPerOrder(item buildOrderItem) int {
+	if item.kind == "unit" && (item.unitID == nameToUnitID["Zergling"] || item.unitID == nameToUnitID["Scourge"]) {
+		return 2
+	}
+	return 1
+}
+
+// results returns the build order as a list of records with the time (as a duration) and the name of each item.
+func (b *buildOrder) results() []Result {
+	results := make([]Result, len(b.items))
+	for i, item := range b.items {
+		results[i] = NewRecordResult([]RecordField{
+			{Name: "time", Value: NewDurationResult(item.frame.Duration().Truncate(time.Second))},
+			{Name: "item", Value: NewStringResult(item.name)},
+		})
+	}
+	return results
+}
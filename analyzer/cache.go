@@ -0,0 +1,278 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// resultCache persists Analyzer results on disk, so that analyzing the same replays again can skip parsing them.
+// There is one JSON file per replay, named after the hash of the replay's contents, containing an entry per
+// Analyzer (with its arguments) and whatever else its results depend on (see cacheKey). Entries for an older
+// Analyzer Version are ignored. The file also has the IDs of the replay's players once known, so that per-player
+// mode can restore every player's results without parsing the replay.
+type resultCache struct {
+	dir              string
+	analyzerWrappers []analyzerWrapper
+	mutex            sync.Mutex // N.B. replays with the same contents share a file
+}
+
+// cacheEntry is the content of a replay's cache file.
+type cacheEntry struct {
+	hash      string
+	playerIDs []byte // of the players that are not observers; nil if unknown
+	results   map[string]cachedResult
+}
+
+// cacheFile is the JSON encoding of a cacheEntry.
+type cacheFile struct {
+	PlayerIDs []int                   `json:"playerIds,omitempty"`
+	Results   map[string]cachedResult `json:"results"`
+}
+
+type cachedResult struct {
+	Version int         `json:"version"`
+	Result  typedResult `json:"result"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func newResultCache(dir string, analyzerWrappers []analyzerWrapper) *resultCache {
+	return &resultCache{dir: dir, analyzerWrappers: analyzerWrappers}
+}
+
+// load hashes the replay's contents and reads its cache file, if it exists.
+func (c *resultCache) load(replayPath string) (cacheEntry, error) {
+	entry := cacheEntry{results: map[string]cachedResult{}}
+	hash, err := hashFile(replayPath)
+	if err != nil {
+		return entry, fmt.Errorf("error hashing replay %v for caching: %v", replayPath, err)
+	}
+	entry.hash = hash
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.read(&entry); err != nil {
+		return entry, fmt.Errorf("error reading cached results for replay %v: %v", replayPath, err)
+	}
+	return entry, nil
+}
+
+// restore marks as done all analyzers that have a cached result for their current Version.
+func (c *resultCache) restore(entry cacheEntry, analysis *replayAnalysis, ctx Context, replayPath string) {
+	for i, aw := range c.analyzerWrappers {
+		cached, ok := entry.results[cacheKey(aw, ctx, replayPath)]
+		if !ok || cached.Version != aw.analyzer.Version() {
+			continue
+		}
+		result, err := cached.Result.toResult()
+		if err != nil {
+			continue // N.B. recompute it
+		}
+		if cached.Error != "" {
+			err = fmt.Errorf("%v", cached.Error)
+		}
+		analysis.finish(i, result, err)
+	}
+}
+
+// save merges the results of all done analyzers into the replay's cache file.
+func (c *resultCache) save(entry cacheEntry, analysis *replayAnalysis, ctx Context, replayPath string) error {
+	if entry.hash == "" {
+		return nil // replay couldn't be hashed; already reported on load
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	playerIDs := entry.playerIDs
+	if err := c.read(&entry); err != nil { // N.B. another replay with the same contents might have saved meanwhile
+		entry.results = map[string]cachedResult{}
+	}
+	if playerIDs != nil {
+		entry.playerIDs = playerIDs
+	}
+	for i, aw := range c.analyzerWrappers {
+		if !analysis.done[i] {
+			continue
+		}
+		result, err := analysis.results[i].toTyped()
+		if err != nil {
+			return fmt.Errorf("error encoding cached results for replay %v: %v", replayPath, err)
+		}
+		cached := cachedResult{Version: aw.analyzer.Version(), Result: result}
+		if analysis.errs[i] != nil {
+			cached.Error = analysis.errs[i].Error()
+		}
+		entry.results[cacheKey(aw, ctx, replayPath)] = cached
+	}
+	file := cacheFile{Results: entry.results}
+	for _, playerID := range entry.playerIDs {
+		file.PlayerIDs = append(file.PlayerIDs, int(playerID))
+	}
+	bs, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("error encoding cached results for replay %v: %v", replayPath, err)
+	}
+	// Write to a temporary file and rename it, so that an interrupted run never leaves a corrupt cache file
+	tmp, err := ioutil.TempFile(c.dir, entry.hash+".tmp")
+	if err != nil {
+		return fmt.Errorf("error caching results for replay %v: %v", replayPath, err)
+	}
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error caching results for replay %v: %v", replayPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error caching results for replay %v: %v", replayPath, err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(entry.hash)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error caching results for replay %v: %v", replayPath, err)
+	}
+	return nil
+}
+
+// read reads the cache file of the entry's hash onto its player IDs and results. A missing cache file is not an
+// error.
+func (c *resultCache) read(entry *cacheEntry) error {
+	bs, err := ioutil.ReadFile(c.path(entry.hash))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	file := cacheFile{}
+	if err := json.Unmarshal(bs, &file); err != nil {
+		return err
+	}
+	if file.Results == nil {
+		file.Results = map[string]cachedResult{}
+	}
+	entry.results = file.Results
+	if file.PlayerIDs != nil {
+		entry.playerIDs = make([]byte, len(file.PlayerIDs))
+		for i, playerID := range file.PlayerIDs {
+			entry.playerIDs[i] = byte(playerID)
+		}
+	}
+	return nil
+}
+
+func (c *resultCache) path(hash string) string {
+	return filepath.Join(c.dir, hash+".json")
+}
+
+// cacheKey identifies an Analyzer (with its arguments) in a replay's cache file. Since the file is keyed by the
+// replay's contents, the replay's path and the parts of the Context are only part of the key for Analyzers whose
+// results depend on them (see cacheKeyParts). The Versions of the Analyzer's dependencies are part of the key as well,
+// so that updating a dependency recomputes its dependents.
+func cacheKey(aw analyzerWrapper, ctx Context, replayPath string) string {
+	var (
+		parts    cacheKeyParts
+		versions = map[string]int{}
+	)
+	findCacheKeyDependencies(aw.analyzer.Name(), &parts, versions)
+	key := aw.displayName
+	if len(versions) > 0 {
+		deps := make([]string, 0, len(versions))
+		for name, version := range versions {
+			deps = append(deps, fmt.Sprintf("%v@%v", name, version))
+		}
+		sort.Strings(deps)
+		key += fmt.Sprintf("|deps=%v", strings.Join(deps, ","))
+	}
+	if parts.path {
+		key += fmt.Sprintf("|path=%v", replayPath)
+	}
+	if parts.me && ctx.player != nil {
+		key += fmt.Sprintf("|player=%v", ctx.player.ID)
+	} else if parts.me {
+		me := make([]string, 0, len(ctx.Me))
+		for name := range ctx.Me {
+			me = append(me, name)
+		}
+		sort.Strings(me)
+		key += fmt.Sprintf("|me=%v", strings.Join(me, ","))
+	}
+	if parts.openings && ctx.Openings != nil {
+		bs, _ := json.Marshal(ctx.Openings) // N.B. cannot fail
+		key += fmt.Sprintf("|openings=%x", sha256.Sum256(bs))
+	}
+	if parts.maps && ctx.Maps != nil {
+		bs, _ := json.Marshal(ctx.Maps) // N.B. cannot fail
+		key += fmt.Sprintf("|maps=%x", sha256.Sum256(bs))
+	}
+	if parts.mapNameRules && ctx.MapNameRules != nil {
+		patterns := make([]string, len(ctx.MapNameRules))
+		for i, rule := range ctx.MapNameRules {
+			patterns[i] = rule.String()
+		}
+		key += fmt.Sprintf("|map-name-rules=%x", sha256.Sum256([]byte(strings.Join(patterns, "\n"))))
+	}
+	return key
+}
+
+// cacheKeyParts are the inputs besides the replay's contents and the Analyzer's arguments that an Analyzer's
+// results may depend on.
+type cacheKeyParts struct {
+	path         bool // e.g. replay-name
+	me           bool // the -me player (or the player in per-player mode), i.e. my-* and opponent-* Analyzers
+	openings     bool
+	maps         bool
+	mapNameRules bool
+}
+
+// analyzerCacheKeyParts are the cacheKeyParts of the Analyzers that depend on any, other than the -me player.
+var analyzerCacheKeyParts = map[string]cacheKeyParts{
+	"replay-name":         {path: true},
+	"replay-path":         {path: true},
+	"my-opening":          {me: true, openings: true},
+	"canonical-map":       {maps: true, mapNameRules: true},
+	"map-name-normalized": {mapNameRules: true},
+	"map-name-is":         {mapNameRules: true},
+}
+
+// findCacheKeyDependencies merges the cacheKeyParts of the Analyzer and of its transitive dependencies onto parts,
+// and adds the Versions of its transitive dependencies to versions.
+func findCacheKeyDependencies(name string, parts *cacheKeyParts, versions map[string]int) {
+	own := analyzerCacheKeyParts[name]
+	parts.path = parts.path || own.path
+	parts.me = parts.me || own.me || strings.HasPrefix(name, "my-") || strings.HasPrefix(name, "opponent-")
+	parts.openings = parts.openings || own.openings
+	parts.maps = parts.maps || own.maps
+	parts.mapNameRules = parts.mapNameRules || own.mapNameRules
+	a, ok := Analyzers[name]
+	if !ok {
+		return
+	}
+	for dependency := range a.DependsOn() {
+		if _, ok := versions[dependency]; ok {
+			continue
+		}
+		if d, ok := Analyzers[dependency]; ok {
+			versions[dependency] = d.Version()
+		}
+		findCacheKeyDependencies(dependency, parts, versions)
+	}
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/icza/screp/rep"
+)
+
+// ggRegexp matches chat messages saying "good game", e.g. "gg", "GG wp", "ggwp" or "good game!".
+var ggRegexp = regexp.MustCompile(`(?i)\b(gg+(\s*wp)?|good\s*game)\b`)
+
+// chatLog returns the in-game chat as a list of records with the time (as a duration), the sender's name and the
+// message of each chat message.
+func chatLog(replay *rep.Replay) []Result {
+	results := make([]Result, len(replay.Computed.ChatCmds))
+	for i, c := range replay.Computed.ChatCmds {
+		results[i] = NewRecordResult([]RecordField{
+			{Name: "time", Value: NewDurationResult(c.Frame.Duration().Truncate(time.Second))},
+			{Name: "sender", Value: NewStringResult(chatSenderName(replay, c.PlayerID))},
+			{Name: "message", Value: NewStringResult(c.Message)},
+		})
+	}
+	return results
+}
+
+// findFirstGGSender returns the name of the first player (or observer) who said "good game" (see ggRegexp), if
+// anybody did.
+func findFirstGGSender(replay *rep.Replay) (string, bool) {
+	for _, c := range replay.Computed.ChatCmds {
+		if ggRegexp.MatchString(c.Message) {
+			return chatSenderName(replay, c.PlayerID), true
+		}
+	}
+	return "", false
+}
+
+// chatContains returns true if any chat message matches the regular expression.
+func chatContains(replay *rep.Replay, r *regexp.Regexp) bool {
+	for _, c := range replay.Computed.ChatCmds {
+		if r.MatchString(c.Message) {
+			return true
+		}
+	}
+	return false
+}
+
+func chatSenderName(replay *rep.Replay, playerID byte) string {
+	if p, ok := replay.Header.PIDPlayers[playerID]; ok {
+		return p.Name
+	}
+	return "unknown"
+}
@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// comparison is the condition of a filter on an Analyzer with a numeric Result, e.g. ">=150" in
+// -filter--my-apm '>=150'. Durations are compared in seconds.
+type comparison struct {
+	operator string
+	value    float64
+}
+
+// comparisonOperators is in matching order, i.e. longer operators first.
+var comparisonOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// parseComparison parses a comparison, i.e. an operator followed by a number, or by m:ss for durations.
+func parseComparison(s string) (comparison, error) {
+	s = strings.TrimSpace(s)
+	for _, operator := range comparisonOperators {
+		if !strings.HasPrefix(s, operator) {
+			continue
+		}
+		value, err := parseComparisonValue(strings.TrimSpace(s[len(operator):]))
+		if err != nil {
+			return comparison{}, fmt.Errorf("invalid comparison %q: %v", s, err)
+		}
+		return comparison{operator, value}, nil
+	}
+	return comparison{}, fmt.Errorf("invalid comparison %q: expected one of %v followed by a number, e.g. >=150",
+		s, strings.Join(comparisonOperators, " "))
+}
+
+func parseComparisonValue(s string) (float64, error) {
+	if i := strings.Index(s, ":"); i >= 0 {
+		minutes, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid minutes in %q", s)
+		}
+		seconds, err := strconv.Atoi(s[i+1:])
+		if err != nil || seconds < 0 || seconds >= 60 {
+			return 0, fmt.Errorf("invalid seconds in %q", s)
+		}
+		return float64(minutes*60 + seconds), nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return value, nil
+}
+
+// matches returns true if the given Result satisfies this comparison. Null and non-numeric Results never match.
+func (c comparison) matches(result Result) bool {
+	var value float64
+	switch result.Type() {
+	case ResultTypeInt:
+		value = float64(result.Int())
+	case ResultTypeFloat:
+		value = result.Float()
+	case ResultTypeDuration:
+		value = result.Duration().Seconds()
+	default:
+		return false
+	}
+	switch c.operator {
+	case ">=":
+		return value >= c.value
+	case "<=":
+		return value <= c.value
+	case "!=":
+		return value != c.value
+	case ">":
+		return value > c.value
+	case "<":
+		return value < c.value
+	}
+	return value == c.value
+}
+
+func (c comparison) String() string {
+	return c.operator + strconv.FormatFloat(c.value, 'f', -1, 64)
+}
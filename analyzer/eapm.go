@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"math"
+	"time"
+
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// Thresholds for classifying commands as ineffective, in frames (there are ~24 frames per second on Fastest).
+const (
+	eapmFastCancelFrames      = 20 // a cancel this soon after the previous command is likely a misclick
+	eapmFastRepetitionFrames  = 10 // the same order this soon after the previous one is likely spam
+	eapmFastReselectionFrames = 8  // a selection this soon after the previous selection is likely hotkey mashing
+)
+
+// hotkeyTypeIDSelect is the ID of the Select HotkeyType, as opposed to Assign and Add.
+const hotkeyTypeIDSelect byte = 0x01
+
+// eapmCounter classifies the commands of a player as effective or ineffective, to calculate the player's EAPM
+// (Effective Actions Per Minute) and redundancy (ratio of ineffective commands), overall and per minute. A command is ineffective if it's:
+//
+//   - a selection (including hotkey selects) too soon after the previous selection,
+//   - a hotkey assign/add repeating the previous command,
+//   - an order (e.g. right-click, stop, hold position) of the same type too soon after the previous one,
+//   - a cancel too soon after the previous command.
+type eapmCounter struct {
+	playerID  byte
+	prev      repcmd.Cmd
+	count     int
+	effective int
+	lastFrame repcore.Frame
+
+	// apmPerMinute and eapmPerMinute are the number of (effective) commands in each minute of the game, until the
+	// player's last command. Thus, the last minute may be partial.
+	apmPerMinute  []int
+	eapmPerMinute []int
+}
+
+func newEAPMCounter(playerID byte) *eapmCounter {
+	return &eapmCounter{playerID: playerID}
+}
+
+// add classifies the command if it's the player's.
+func (c *eapmCounter) add(command repcmd.Cmd) {
+	if command.BaseCmd().PlayerID != c.playerID {
+		return
+	}
+	minute := int(command.BaseCmd().Frame.Duration() / time.Minute)
+	for len(c.apmPerMinute) <= minute {
+		c.apmPerMinute = append(c.apmPerMinute, 0)
+		c.eapmPerMinute = append(c.eapmPerMinute, 0)
+	}
+	if !isIneffectiveCommand(command, c.prev) {
+		c.effective++
+		c.eapmPerMinute[minute]++
+	}
+	c.count++
+	c.apmPerMinute[minute]++
+	c.lastFrame = command.BaseCmd().Frame
+	c.prev = command
+}
+
+// eapm is calculated like screp's APM, i.e. over the time until the player's last command.
+func (c *eapmCounter) eapm() int {
+	if c.lastFrame == 0 {
+		return 0
+	}
+	return int(float64(c.effective)/c.lastFrame.Duration().Minutes() + 0.5)
+}
+
+// redundancy is the ratio of ineffective commands, rounded to 2 decimals.
+func (c *eapmCounter) redundancy() float64 {
+	if c.count == 0 {
+		return 0
+	}
+	return math.Round(float64(c.count-c.effective)/float64(c.count)*100) / 100
+}
+
+// result returns the counts, EAPM, redundancy and counts per minute as a record Result, for the my-actions Analyzer.
+func (c *eapmCounter) result() Result {
+	return NewRecordResult([]RecordField{
+		{Name: "actions", Value: NewIntResult(c.count)},
+		{Name: "effective-actions", Value: NewIntResult(c.effective)},
+		{Name: "eapm", Value: NewIntResult(c.eapm())},
+		{Name: "redundancy", Value: NewFloatResult(c.redundancy())},
+		{Name: "apm-per-minute", Value: NewListResult(perMinuteResults(c.apmPerMinute))},
+		{Name: "eapm-per-minute", Value: NewListResult(perMinuteResults(c.eapmPerMinute))},
+	})
+}
+
+// perMinuteResults returns the given per minute counts as a list of Int Results.
+func perMinuteResults(perMinute []int) []Result {
+	results := make([]Result, len(perMinute))
+	for i, count := range perMinute {
+		results[i] = NewIntResult(count)
+	}
+	return results
+}
+
+func isIneffectiveCommand(command, prev repcmd.Cmd) bool {
+	if prev == nil {
+		return false
+	}
+	var (
+		typeID     = command.BaseCmd().Type.ID
+		prevTypeID = prev.BaseCmd().Type.ID
+		delta      = command.BaseCmd().Frame - prev.BaseCmd().Frame
+	)
+	switch typeID {
+	case repcmd.TypeIDSelect, repcmd.TypeIDSelectAdd, repcmd.TypeIDSelectRemove, repcmd.TypeIDSelect121:
+		return isSelectionCommand(prev) && delta <= eapmFastReselectionFrames
+	case repcmd.TypeIDHotkey:
+		if isSelectionCommand(command) {
+			return isSelectionCommand(prev) && delta <= eapmFastReselectionFrames
+		}
+		hotkey, ok := command.(*repcmd.HotkeyCmd)
+		prevHotkey, prevOk := prev.(*repcmd.HotkeyCmd)
+		return ok && prevOk && prevHotkey.HotkeyType.ID == hotkey.HotkeyType.ID && prevHotkey.Group == hotkey.Group
+	case repcmd.TypeIDRightClick, repcmd.TypeIDTargetedOrder, repcmd.TypeIDStop, repcmd.TypeIDHoldPosition,
+		repcmd.TypeIDReturnCargo, repcmd.TypeIDUnloadAll, repcmd.TypeIDSiege, repcmd.TypeIDUnsiege,
+		repcmd.TypeIDBurrow, repcmd.TypeIDUnburrow, repcmd.TypeIDCloack, repcmd.TypeIDDecloack:
+		return typeID == prevTypeID && delta <= eapmFastRepetitionFrames
+	case repcmd.TypeIDCancelBuild, repcmd.TypeIDCancelMorph, repcmd.TypeIDCancelTrain, repcmd.TypeIDCancelTech,
+		repcmd.TypeIDCancelUpgrade, repcmd.TypeIDCancelAddon, repcmd.TypeIDCancelNuke:
+		return delta <= eapmFastCancelFrames
+	}
+	return false
+}
+
+func isSelectionCommand(command repcmd.Cmd) bool {
+	switch command.BaseCmd().Type.ID {
+	case repcmd.TypeIDSelect, repcmd.TypeIDSelectAdd, repcmd.TypeIDSelectRemove, repcmd.TypeIDSelect121:
+		return true
+	case repcmd.TypeIDHotkey:
+		hotkey, ok := command.(*repcmd.HotkeyCmd)
+		return ok && hotkey.HotkeyType.ID == hotkeyTypeIDSelect
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"math"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// Distances for telling expansions from macro hatcheries (and the like), in tiles.
+const (
+	expansionMaxMineralDistance = 10 // an expansion is placed this close to some mineral field
+	expansionMinBaseDistance    = 10 // an expansion is placed this far from the player's other bases
+)
+
+// expansionTracker accumulates the town halls (i.e. Command Centers, Nexuses and Hatcheries) a player builds, and
+// the first gas the player takes. A town hall is an expansion if it's placed near mineral fields and away from the
+// player's start location and earlier expansions; otherwise it's e.g. a macro hatchery. Repeated BuildCmds of the
+// same town hall on the same position are only counted once.
+type expansionTracker struct {
+	playerID        byte
+	mineralFields   []repcore.Point // in tiles
+	bases           []repcore.Point // start location and expansions, in tiles
+	placed          map[buildingPlacement]struct{}
+	townHalls       int
+	expansionFrames []repcore.Frame
+	firstGasFrame   repcore.Frame // 0 if the player didn't take gas yet
+}
+
+func newExpansionTracker(replay *rep.Replay, playerID byte) *expansionTracker {
+	t := &expansionTracker{playerID: playerID, placed: map[buildingPlacement]struct{}{}}
+	if replay.MapData != nil {
+		for _, mineralField := range replay.MapData.MineralFields {
+			t.mineralFields = append(t.mineralFields, pixelsToTile(mineralField))
+		}
+	}
+	if start, ok := findStartLocationTile(replay, playerID); ok {
+		t.bases = append(t.bases, start)
+	}
+	return t
+}
+
+// add adds the command if it's a town hall or gas BuildCmd of the player.
+func (t *expansionTracker) add(command repcmd.Cmd) {
+	c, ok := command.(*repcmd.BuildCmd)
+	if !ok || c.PlayerID != t.playerID {
+		return
+	}
+	switch c.Unit.ID {
+	case nameToUnitID["Extractor"], nameToUnitID["Refinery"], nameToUnitID["Assimilator"]:
+		if t.firstGasFrame == 0 {
+			t.firstGasFrame = c.Frame
+		}
+	case nameToUnitID["Command Center"], nameToUnitID["Nexus"], nameToUnitID["Hatchery"]:
+		placement := buildingPlacement{c.Unit.ID, c.Pos}
+		if _, ok := t.placed[placement]; ok {
+			return
+		}
+		t.placed[placement] = struct{}{}
+		t.townHalls++
+		if t.isExpansion(c.Pos) {
+			t.bases = append(t.bases, c.Pos)
+			t.expansionFrames = append(t.expansionFrames, c.Frame)
+		}
+	}
+}
+
+func (t *expansionTracker) isExpansion(pos repcore.Point) bool {
+	for _, base := range t.bases {
+		if tileDistance(base, pos) < expansionMinBaseDistance {
+			return false
+		}
+	}
+	if len(t.mineralFields) == 0 {
+		return true // N.B. no map data to tell otherwise
+	}
+	for _, mineralField := range t.mineralFields {
+		if tileDistance(mineralField, pos) <= expansionMaxMineralDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// expandedBeforeFirstGas returns whether the player expanded before taking gas, and whether that's known yet.
+func (t *expansionTracker) expandedBeforeFirstGas() (bool, bool) {
+	switch {
+	case len(t.expansionFrames) > 0:
+		return t.firstGasFrame == 0 || t.expansionFrames[0] < t.firstGasFrame, true
+	case t.firstGasFrame != 0:
+		return false, true
+	}
+	return false, false
+}
+
+func tileDistance(a, b repcore.Point) float64 {
+	return math.Hypot(float64(a.X)-float64(b.X), float64(a.Y)-float64(b.Y))
+}
@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/icza/screp/rep"
+)
+
+// KnownMap is an entry of a user-maintained catalog of known maps, for the canonical-map Analyzer. A replay is on
+// the map if its map fingerprint (see map-fingerprint) is one of Fingerprints, or otherwise if its cleaned map name
+// is Name or one of Names (see map-name-normalized; ignoring case). Several versions of a map can thus be grouped
+// under one Name.
+//
+// For example, {"name": "Fighting Spirit", "fingerprints": ["9f86d081884c7d65"], "names": ["| iCCup | Fighting
+// Spirit 1.3"]}.
+type KnownMap struct {
+	Name         string   `json:"name"`
+	Fingerprints []string `json:"fingerprints,omitempty"`
+	Names        []string `json:"names,omitempty"`
+}
+
+// ParseMapCatalog parses a JSON array of KnownMaps, e.g. from a catalog file, and validates them.
+func ParseMapCatalog(r io.Reader) ([]KnownMap, error) {
+	maps := []KnownMap{}
+	if err := json.NewDecoder(r).Decode(&maps); err != nil {
+		return nil, fmt.Errorf("invalid maps JSON: %v", err)
+	}
+	for i, m := range maps {
+		if m.Name == "" {
+			return nil, fmt.Errorf("map #%v has no name", i+1)
+		}
+		for j, fingerprint := range m.Fingerprints {
+			maps[i].Fingerprints[j] = strings.ToLower(strings.TrimSpace(fingerprint))
+		}
+	}
+	return maps, nil
+}
+
+// findCanonicalMap returns the name of the catalog's map matching the fingerprint, or otherwise the map name
+// (normalized with the rules, see normalizeMapName). Returns false if none does.
+func findCanonicalMap(maps []KnownMap, rules []*regexp.Regexp, fingerprint, mapName string) (string, bool) {
+	for _, m := range maps {
+		for _, f := range m.Fingerprints {
+			if fingerprint != "" && f == fingerprint {
+				return m.Name, true
+			}
+		}
+	}
+	normalizedName := normalizeMapName(mapName, rules)
+	for _, m := range maps {
+		for _, name := range append([]string{m.Name}, m.Names...) {
+			if strings.EqualFold(normalizeMapName(name, rules), normalizedName) {
+				return m.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mapFingerprint returns a stable hash (16 hex characters) of the map's terrain and resources, i.e. its dimensions,
+// tileset, tiles, mineral fields, geysers and start locations, so that different versions of a map with the same
+// name have different fingerprints, and the same map with different names has the same one. Returns false if the
+// replay has no map data.
+func mapFingerprint(replay *rep.Replay) (string, bool) {
+	md := replay.MapData
+	if md == nil {
+		return "", false
+	}
+	h := sha256.New()
+	write := func(data interface{}) { binary.Write(h, binary.LittleEndian, data) } // N.B. cannot fail on hashes
+	write([]uint16{replay.Header.MapWidth, replay.Header.MapHeight})
+	if md.TileSet != nil {
+		write(md.TileSet.ID)
+	}
+	write(uint32(len(md.Tiles)))
+	write(md.Tiles)
+	write(uint32(len(md.MineralFields)))
+	for _, mineralField := range md.MineralFields {
+		write([]uint16{mineralField.X, mineralField.Y})
+	}
+	write(uint32(len(md.Geysers)))
+	for _, geyser := range md.Geysers {
+		write([]uint16{geyser.X, geyser.Y})
+	}
+	write(uint32(len(md.StartLocations)))
+	for _, startLocation := range md.StartLocations {
+		write([]uint16{startLocation.X, startLocation.Y})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)[:8]), true
+}
+
+// DefaultMapNameRules are the MapNameRules used when the Context has none. They remove (case-insensitively):
+//
+//   - leading tags between pipes, brackets or parentheses, e.g. "| iCCup |", "[ASL]" or "(4)",
+//   - leading tournament prefixes, e.g. "iCCup " or "ASL: ",
+//   - trailing version numbers, e.g. " 1.3", "v2.0" or " ver 1.1b".
+var DefaultMapNameRules = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^[|\[(][^|\])]*[|\])]`),
+	regexp.MustCompile(`(?i)^(iccup|asl|ksl|osl|msl|wcg)\b[\s:_-]*`),
+	regexp.MustCompile(`(?i)[\s_-]*(ver\.?\s*|v)?\d+(\.\d+)+[a-z]?$`),
+}
+
+// ParseMapNameRules parses a JSON array of regular expressions, e.g. from a rules file, whose matches are removed
+// from map names by normalizeMapName. See DefaultMapNameRules for examples.
+func ParseMapNameRules(r io.Reader) ([]*regexp.Regexp, error) {
+	patterns := []string{}
+	if err := json.NewDecoder(r).Decode(&patterns); err != nil {
+		return nil, fmt.Errorf("invalid map name rules JSON: %v", err)
+	}
+	rules := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		rule, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("map name rule #%v is an invalid regular expression: %v", i+1, err)
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// normalizeMapName cleans the map name (see cleanMapName) and removes the matches of the rules from it (e.g.
+// tournament prefixes and version numbers), until none matches. If nothing would be left, the cleaned name is
+// returned instead.
+func normalizeMapName(name string, rules []*regexp.Regexp) string {
+	cleanName := cleanMapName(name)
+	normalized := cleanName
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			if n := cleanMapName(rule.ReplaceAllString(normalized, "")); n != normalized {
+				normalized, changed = n, true
+			}
+		}
+	}
+	if normalized == "" {
+		return cleanName
+	}
+	return normalized
+}
+
+// cleanMapName strips the control characters (e.g. color codes) from a map name, and trims and collapses its
+// spacing.
+func cleanMapName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// mapNameRules returns the Context's MapNameRules, or the default ones.
+func mapNameRules(ctx Context) []*regexp.Regexp {
+	if ctx.MapNameRules == nil {
+		return DefaultMapNameRules
+	}
+	return ctx.MapNameRules
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/icza/screp/rep"
+)
+
+// findTeamRaces returns the race letters of the players of each team of the replay (e.g. "PT"), sorted within each
+// team. Observers are ignored, and on game types without teams (see isGameTypeWithoutTeams) every player is on their
+// own team. The team of the given player comes first, and the rest are sorted lexicographically; if the player is
+// not present (e.g. 127), all teams are sorted lexicographically.
+func findTeamRaces(replay *rep.Replay, playerID byte) []string {
+	var (
+		ignoreTeams = isGameTypeWithoutTeams(replay.Header.Type)
+		teamLetters = map[byte][]string{}
+		teamOrder   = []byte{}
+		myTeam      = -1
+	)
+	for i, p := range replay.Header.Players {
+		if isObserver(p) {
+			continue
+		}
+		team := p.Team
+		if ignoreTeams {
+			team = byte(i)
+		}
+		if _, ok := teamLetters[team]; !ok {
+			teamOrder = append(teamOrder, team)
+		}
+		teamLetters[team] = append(teamLetters[team], strings.ToUpper(string(p.Race.Letter)))
+		if p.ID == playerID {
+			myTeam = int(team)
+		}
+	}
+	var (
+		teams  = []string{}
+		others = []string{}
+	)
+	for _, team := range teamOrder {
+		sort.Strings(teamLetters[team])
+		if int(team) == myTeam {
+			teams = append(teams, strings.Join(teamLetters[team], ""))
+		} else {
+			others = append(others, strings.Join(teamLetters[team], ""))
+		}
+	}
+	sort.Strings(others)
+	return append(teams, others...)
+}
+
+// formatMatchup formats the races of each team as a matchup, e.g. "TvZ" when every team has a single player, or
+// "PT vs ZZ" otherwise.
+func formatMatchup(teams []string) string {
+	for _, team := range teams {
+		if len(team) > 1 {
+			return strings.Join(teams, " vs ")
+		}
+	}
+	return strings.Join(teams, "v")
+}
+
+// parseMatchup parses a matchup or matchup pattern into the races of each team, e.g. "TvZ", "pt vs zz" or "Z? v ??",
+// where ? is any race. Returns false if it's invalid.
+func parseMatchup(s string) ([]string, bool) {
+	s = strings.Replace(strings.ToUpper(s), " ", "", -1)
+	teams := strings.Split(strings.Replace(s, "VS", "V", -1), "V")
+	if len(teams) < 2 {
+		return nil, false
+	}
+	for _, team := range teams {
+		if team == "" || strings.Trim(team, "PTZR?") != "" {
+			return nil, false
+		}
+	}
+	return teams, true
+}
+
+// matchupMatches returns true if the races of each team match the pattern's, in any team order. If isFirstFixed,
+// the first team must match the pattern's first team (e.g. for the -me player's team).
+func matchupMatches(teams, pattern []string, isFirstFixed bool) bool {
+	if len(teams) != len(pattern) {
+		return false
+	}
+	if isFirstFixed {
+		return len(teams) > 0 && teamMatches(teams[0], pattern[0]) && matchupMatches(teams[1:], pattern[1:], false)
+	}
+	if len(teams) == 0 {
+		return true
+	}
+	for i := range teams {
+		if !teamMatches(teams[i], pattern[0]) {
+			continue
+		}
+		rest := append(append([]string{}, teams[:i]...), teams[i+1:]...)
+		if matchupMatches(rest, pattern[1:], false) {
+			return true
+		}
+	}
+	return false
+}
+
+// teamMatches returns true if the races of a team match a pattern's, in any order, e.g. "PZ" matches "Z?".
+func teamMatches(team, pattern string) bool {
+	if len(team) != len(pattern) {
+		return false
+	}
+	for _, letter := range "PTZR" {
+		if strings.Count(pattern, string(letter)) > strings.Count(team, string(letter)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/icza/screp/rep/repcore"
+)
+
+// Opening is a rule for labelling a player's opening (e.g. "9 pool") by the my-opening Analyzer. A player's opening
+// is the first Opening (in order) for the player's race whose conditions all hold on the player's build order. Only
+// the first openingHorizon of the game is considered.
+type Opening struct {
+	Name       string             `json:"name"`
+	Race       string             `json:"race"`
+	Conditions []OpeningCondition `json:"conditions"`
+}
+
+// OpeningCondition holds if the player ordered between Min (default 1, or 0 if there's a Max) and Max (default
+// unlimited) of Item before Before. Item is a build order item, i.e. a building, unit, tech or upgrade name such as "Spawning Pool". Before is
+// either a game time like "2:30", or another item, meaning before that item was first ordered (or anytime if it
+// never was). If Before is empty, it means anytime.
+//
+// For example, "Spawning Pool before Hatchery" is {"item": "Spawning Pool", "before": "Hatchery"}, and "at most 5
+// Drones before the Spawning Pool" is {"item": "Drone", "before": "Spawning Pool", "max": 5}.
+type OpeningCondition struct {
+	Item   string `json:"item"`
+	Before string `json:"before,omitempty"`
+	Min    *int   `json:"min,omitempty"`
+	Max    *int   `json:"max,omitempty"`
+}
+
+// openingHorizon is how much of the game is considered for classifying openings.
+const openingHorizon = 6 * time.Minute
+
+// DefaultOpenings are the Openings used when the Context has none.
+var DefaultOpenings = []Opening{
+	{"4 pool", "Zerg", []OpeningCondition{
+		{Item: "Drone", Before: "Spawning Pool", Max: intPtr(1)},
+		{Item: "Spawning Pool", Before: "Hatchery"},
+	}},
+	{"9 pool", "Zerg", []OpeningCondition{
+		{Item: "Drone", Before: "Spawning Pool", Min: intPtr(4), Max: intPtr(5)},
+		{Item: "Spawning Pool", Before: "Hatchery"},
+	}},
+	{"overpool", "Zerg", []OpeningCondition{
+		{Item: "Overlord", Before: "Spawning Pool"},
+		{Item: "Drone", Before: "Spawning Pool", Max: intPtr(7)},
+		{Item: "Spawning Pool", Before: "Hatchery"},
+	}},
+	{"12 pool", "Zerg", []OpeningCondition{
+		{Item: "Spawning Pool", Before: "Hatchery"},
+	}},
+	{"12 hatch", "Zerg", []OpeningCondition{
+		{Item: "Hatchery", Before: "Spawning Pool"},
+		{Item: "Drone", Before: "Hatchery", Max: intPtr(9)},
+	}},
+	{"3 hatch before pool", "Zerg", []OpeningCondition{
+		{Item: "Hatchery", Before: "Spawning Pool", Min: intPtr(2)},
+	}},
+	{"BBS", "Terran", []OpeningCondition{
+		{Item: "Barracks", Before: "Supply Depot", Min: intPtr(2)},
+	}},
+	{"CC first", "Terran", []OpeningCondition{
+		{Item: "Command Center", Before: "Barracks"},
+	}},
+	{"1 rax FE", "Terran", []OpeningCondition{
+		{Item: "Barracks", Before: "Command Center", Max: intPtr(1)},
+		{Item: "Command Center", Before: "Factory"},
+		{Item: "Command Center", Before: "3:30"},
+	}},
+	{"2 rax", "Terran", []OpeningCondition{
+		{Item: "Barracks", Before: "Refinery", Min: intPtr(2)},
+	}},
+	{"1 fact", "Terran", []OpeningCondition{
+		{Item: "Factory", Before: "Command Center"},
+	}},
+	{"nexus first", "Protoss", []OpeningCondition{
+		{Item: "Nexus", Before: "Gateway"},
+	}},
+	{"forge FE", "Protoss", []OpeningCondition{
+		{Item: "Forge", Before: "Gateway"},
+		{Item: "Nexus", Before: "Cybernetics Core"},
+	}},
+	{"2 gate", "Protoss", []OpeningCondition{
+		{Item: "Gateway", Before: "Cybernetics Core", Min: intPtr(2)},
+	}},
+	{"1 gate FE", "Protoss", []OpeningCondition{
+		{Item: "Gateway", Before: "Nexus", Max: intPtr(1)},
+		{Item: "Nexus", Before: "3:30"},
+	}},
+	{"1 gate core", "Protoss", []OpeningCondition{
+		{Item: "Gateway", Before: "Cybernetics Core", Max: intPtr(1)},
+		{Item: "Cybernetics Core", Before: "3:00"},
+	}},
+	{"fast expand", "Zerg", []OpeningCondition{{Item: "Hatchery", Before: "3:00"}}},
+	{"fast expand", "Terran", []OpeningCondition{{Item: "Command Center", Before: "3:30"}}},
+	{"fast expand", "Protoss", []OpeningCondition{{Item: "Nexus", Before: "3:30"}}},
+}
+
+// ParseOpenings parses a JSON array of Openings, e.g. from a rules file, and validates them. See DefaultOpenings for
+// examples.
+func ParseOpenings(r io.Reader) ([]Opening, error) {
+	openings := []Opening{}
+	if err := json.NewDecoder(r).Decode(&openings); err != nil {
+		return nil, fmt.Errorf("invalid openings JSON: %v", err)
+	}
+	for i, opening := range openings {
+		if opening.Name == "" {
+			return nil, fmt.Errorf("opening #%v has no name", i+1)
+		}
+		race, ok := raceNameTranslations[strings.ToLower(opening.Race)]
+		if !ok {
+			return nil, fmt.Errorf("opening %v has an invalid race %q; please use Zerg/Protoss/Terran", opening.Name, opening.Race)
+		}
+		openings[i].Race = race
+		for _, condition := range opening.Conditions {
+			if condition.Item == "" {
+				return nil, fmt.Errorf("opening %v has a condition without item", opening.Name)
+			}
+			if !isBuildOrderItemName(condition.Item) {
+				return nil, fmt.Errorf("opening %v has a condition on an unknown item %q; please use a building, unit, tech or upgrade name e.g. Spawning Pool", opening.Name, condition.Item)
+			}
+			if _, err := parseComparisonValue(condition.Before); condition.Before != "" && err != nil && !isBuildOrderItemName(condition.Before) {
+				return nil, fmt.Errorf("opening %v has a condition on %v before an unknown item or time %q; please use a building, unit, tech or upgrade name e.g. Hatchery, or a game time e.g. 2:30", opening.Name, condition.Item, condition.Before)
+			}
+			if condition.Min != nil && condition.Max != nil && *condition.Min > *condition.Max {
+				return nil, fmt.Errorf("opening %v has a condition on %v with min greater than max", opening.Name, condition.Item)
+			}
+		}
+	}
+	return openings, nil
+}
+
+// openingState is the state of the my-opening Analyzer while processing commands.
+type openingState struct {
+	openings   []Opening
+	race       string
+	buildOrder *buildOrder
+}
+
+func (s *openingState) result() Result {
+	name, ok := classifyOpening(s.openings, s.race, s.buildOrder.items)
+	if !ok {
+		return NewNullResult()
+	}
+	return NewStringResult(name)
+}
+
+// classifyOpening returns the name of the first Opening for the given race that the build order satisfies, or
+// false if none does.
+func classifyOpening(openings []Opening, race string, items []buildOrderItem) (string, bool) {
+	for _, opening := range openings {
+		if opening.Race == race && opening.holds(items) {
+			return opening.Name, true
+		}
+	}
+	return "", false
+}
+
+func (o Opening) holds(items []buildOrderItem) bool {
+	for _, condition := range o.Conditions {
+		if !condition.holds(items) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c OpeningCondition) holds(items []buildOrderItem) bool {
+	var (
+		cutoff = openingHorizon
+		count  = 0
+		min    = 1
+	)
+	if c.Before != "" {
+		if seconds, err := parseComparisonValue(c.Before); err == nil {
+			cutoff = time.Duration(seconds) * time.Second
+		} else if frame, ok := firstBuildOrderItemFrame(items, c.Before); ok {
+			cutoff = frame.Duration()
+		}
+	}
+	for _, item := range items {
+		if item.name == c.Item && item.frame.Duration() < cutoff {
+			count++
+		}
+	}
+	if c.Min != nil {
+		min = *c.Min
+	} else if c.Max != nil {
+		min = 0
+	}
+	return count >= min && (c.Max == nil || count <= *c.Max)
+}
+
+// isBuildOrderItemName returns true if the name is the exact name of a building, unit, tech or upgrade, as build order
+// items are named.
+func isBuildOrderItemName(name string) bool {
+	if _, ok := nameToUnitID[name]; ok {
+		return true
+	}
+	for _, n := range append(techNames(), upgradeNames()...) {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func firstBuildOrderItemFrame(items []buildOrderItem, name string) (repcore.Frame, bool) {
+	for _, item := range items {
+		if item.name == name {
+			return item.frame, true
+		}
+	}
+	return 0, false
+}
+
+func intPtr(i int) *int { return &i }
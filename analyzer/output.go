@@ -12,7 +12,7 @@ import (
 // NoOutput: swallows output. Usually used together with AnalyzerExecutor.ExecuteWithResults().
 type Output interface {
 	Pre(analyzerWrappers []analyzerWrapper) error
-	ReplayResults(results []string) error
+	ReplayResults(results []Result) error
 	Post() error
 }
 
@@ -26,12 +26,14 @@ func NewNoOutput() *NoOutput { return &NoOutput{} }
 func (o *NoOutput) Pre(analyzerWrappers []analyzerWrapper) error { return nil }
 
 // ReplayResults runs at each replay result cycle.
-func (o *NoOutput) ReplayResults(results []string) error { return nil }
+func (o *NoOutput) ReplayResults(results []Result) error { return nil }
 
 // Post runs at the end of the replay analyzing cycle.
 func (o *NoOutput) Post() error { return nil }
 
-// CSVOutput outputs results in CSV format with header.
+// CSVOutput outputs results in CSV format with header. Results are output in their textual representation, except
+// null Results of some Analyzers, which keep the placeholders they had before results were typed (see
+// csvNullPlaceholders).
 type CSVOutput struct {
 	w                *csv.Writer
 	analyzerWrappers []analyzerWrapper
@@ -47,7 +49,7 @@ func (o *CSVOutput) Pre(analyzerWrappers []analyzerWrapper) error {
 	o.analyzerWrappers = analyzerWrappers
 	fieldDisplayNames := []string{}
 	for _, wrapper := range analyzerWrappers {
-		if !wrapper.isFilter && !wrapper.isFilterNot {
+		if !wrapper.isFilter && !wrapper.isFilterNot && !wrapper.isHidden {
 			fieldDisplayNames = append(fieldDisplayNames, wrapper.displayName)
 		}
 	}
@@ -55,23 +57,39 @@ func (o *CSVOutput) Pre(analyzerWrappers []analyzerWrapper) error {
 }
 
 // ReplayResults runs at each replay result cycle.
-func (o *CSVOutput) ReplayResults(_results []string) error {
+func (o *CSVOutput) ReplayResults(_results []Result) error {
 	results := []string{}
 	for i, wrapper := range o.analyzerWrappers {
-		if !wrapper.isFilter && !wrapper.isFilterNot {
-			results = append(results, _results[i])
+		if !wrapper.isFilter && !wrapper.isFilterNot && !wrapper.isHidden {
+			results = append(results, csvString(wrapper, _results[i]))
 		}
 	}
 	return o.w.Write(results)
 }
 
+// csvNullPlaceholders are the textual representations in CSV output of the null Results of the Analyzers that didn't
+// output empty values before results were typed, by Analyzer Name.
+var csvNullPlaceholders = map[string]string{
+	"my-apm":                         "-1",
+	"my-first-specific-unit-seconds": "-1",
+	"my-win":                         "unknown",
+}
+
+// csvString returns the textual representation of the Analyzer's result in CSV output.
+func csvString(wrapper analyzerWrapper, result Result) string {
+	if result.IsNull() && wrapper.analyzer != nil {
+		return csvNullPlaceholders[wrapper.analyzer.Name()]
+	}
+	return result.String()
+}
+
 // Post runs at the end of the replay analyzing cycle.
 func (o *CSVOutput) Post() error {
 	o.w.Flush()
 	return o.w.Error()
 }
 
-// JSONOutput outputs results in JSON format as an array of objects.
+// JSONOutput outputs results in JSON format as an array of objects. Results are output as native JSON types.
 type JSONOutput struct {
 	w                 io.Writer
 	firstJSONRow      bool
@@ -97,15 +115,15 @@ func (o *JSONOutput) Pre(analyzerWrappers []analyzerWrapper) error {
 }
 
 // ReplayResults runs at each replay result cycle.
-func (o *JSONOutput) ReplayResults(_results []string) error {
+func (o *JSONOutput) ReplayResults(_results []Result) error {
 	if !o.firstJSONRow {
 		if _, err := o.w.Write([]byte(",\n")); err != nil {
 			return err
 		}
 	}
-	results := map[string]string{}
+	results := map[string]Result{}
 	for i, result := range _results {
-		if !o.analyzerWrappers[i].isFilter && !o.analyzerWrappers[i].isFilterNot {
+		if !o.analyzerWrappers[i].isFilter && !o.analyzerWrappers[i].isFilterNot && !o.analyzerWrappers[i].isHidden {
 			results[o.fieldDisplayNames[i]] = result
 		}
 	}
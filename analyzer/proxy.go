@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// proxyHorizon is how much of the game is considered for detecting proxies, so that e.g. forward bases and
+// cannons in the late game are not proxies.
+const proxyHorizon = 5 * time.Minute
+
+// defaultProxyDistance is the default minimum distance in tiles from a player's start location for a building to
+// be a proxy.
+const defaultProxyDistance = 30
+
+// proxyBuildings are the buildings players proxy, e.g. on proxy gates/barracks, cannon rushes and bunker rushes.
+var proxyBuildings = map[uint16]struct{}{
+	nameToUnitID["Barracks"]:      struct{}{},
+	nameToUnitID["Bunker"]:        struct{}{},
+	nameToUnitID["Factory"]:       struct{}{},
+	nameToUnitID["Forge"]:         struct{}{},
+	nameToUnitID["Gateway"]:       struct{}{},
+	nameToUnitID["Photon Cannon"]: struct{}{},
+	nameToUnitID["Pylon"]:         struct{}{},
+}
+
+// proxyDetector detects which of some players proxy, i.e. order any of the proxyBuildings in the first
+// proxyHorizon of the game, at least minDistance tiles away from their start location and closer to an opponent's
+// start location than to their own. Players without a start location (e.g. on UMS maps) never proxy.
+type proxyDetector struct {
+	minDistance    float64
+	starts         map[byte]repcore.Point // in tiles
+	opponentStarts map[byte][]repcore.Point
+}
+
+func newProxyDetector(replay *rep.Replay, playerIDs []byte, minDistance int) *proxyDetector {
+	d := &proxyDetector{
+		minDistance:    float64(minDistance),
+		starts:         map[byte]repcore.Point{},
+		opponentStarts: map[byte][]repcore.Point{},
+	}
+	for _, playerID := range playerIDs {
+		start, ok := findStartLocationTile(replay, playerID)
+		if !ok {
+			continue
+		}
+		d.starts[playerID] = start
+		for _, opponentID := range findOpponentIDs(replay, Context{player: replay.Header.PIDPlayers[playerID]}) {
+			if opponentStart, ok := findStartLocationTile(replay, opponentID); ok {
+				d.opponentStarts[playerID] = append(d.opponentStarts[playerID], opponentStart)
+			}
+		}
+	}
+	return d
+}
+
+// isProxy returns true if the command is a proxy building of any of the players.
+func (d *proxyDetector) isProxy(command repcmd.Cmd) bool {
+	c, ok := command.(*repcmd.BuildCmd)
+	if !ok {
+		return false
+	}
+	if _, ok := proxyBuildings[c.Unit.ID]; !ok {
+		return false
+	}
+	start, ok := d.starts[c.PlayerID]
+	if !ok || tileDistance(start, c.Pos) < d.minDistance {
+		return false
+	}
+	for _, opponentStart := range d.opponentStarts[c.PlayerID] {
+		if tileDistance(opponentStart, c.Pos) < tileDistance(start, c.Pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPastHorizon returns true if no more proxies can be detected from this command on.
+func (d *proxyDetector) isPastHorizon(command repcmd.Cmd) bool {
+	return command.BaseCmd().Frame.Duration() > proxyHorizon
+}
+
+// findStartLocationTile returns the start location of the player in tiles, if the player has one.
+func findStartLocationTile(replay *rep.Replay, playerID byte) (repcore.Point, bool) {
+	pDesc := findPlayerDesc(replay, playerID)
+	if pDesc == nil || pDesc.StartLocation == nil {
+		return repcore.Point{}, false
+	}
+	return pixelsToTile(*pDesc.StartLocation), true
+}
@@ -0,0 +1,337 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultType is the type of the Result of an Analyzer.
+type ResultType int
+
+// All possible ResultTypes. Note that any Analyzer's Result may be null (e.g. if the -me player is not in the
+// replay), regardless of its ResultType.
+const (
+	ResultTypeNull ResultType = iota
+	ResultTypeBool
+	ResultTypeInt
+	ResultTypeFloat
+	ResultTypeDuration
+	ResultTypeTimestamp
+	ResultTypeString
+	ResultTypeList
+	ResultTypeRecord
+)
+
+var resultTypeNames = map[ResultType]string{
+	ResultTypeNull:      "null",
+	ResultTypeBool:      "bool",
+	ResultTypeInt:       "int",
+	ResultTypeFloat:     "float",
+	ResultTypeDuration:  "duration",
+	ResultTypeTimestamp: "timestamp",
+	ResultTypeString:    "string",
+	ResultTypeList:      "list",
+	ResultTypeRecord:    "record",
+}
+
+func (t ResultType) String() string { return resultTypeNames[t] }
+
+// Result is the typed result of an Analyzer on a replay. The zero value is a null Result (i.e. absent).
+// String() is its textual representation, used in CSV output, and it marshals into the native JSON type.
+type Result struct {
+	typ    ResultType
+	value  interface{}
+	layout string // only for timestamps
+}
+
+// NewNullResult creates an absent Result, e.g. when the -me player is not in the replay.
+func NewNullResult() Result { return Result{} }
+
+// NewBoolResult creates a bool Result.
+func NewBoolResult(b bool) Result { return Result{typ: ResultTypeBool, value: b} }
+
+// NewIntResult creates an int Result.
+func NewIntResult(i int) Result { return Result{typ: ResultTypeInt, value: i} }
+
+// NewFloatResult creates a float Result.
+func NewFloatResult(f float64) Result { return Result{typ: ResultTypeFloat, value: f} }
+
+// NewDurationResult creates a duration Result (e.g. game time). It is represented as m:ss in text, and as a
+// number of seconds in JSON.
+func NewDurationResult(d time.Duration) Result { return Result{typ: ResultTypeDuration, value: d} }
+
+// NewTimestampResult creates a timestamp Result, represented with the given time.Format layout both in text and JSON.
+func NewTimestampResult(t time.Time, layout string) Result {
+	return Result{typ: ResultTypeTimestamp, value: t, layout: layout}
+}
+
+// NewStringResult creates a string Result.
+func NewStringResult(s string) Result { return Result{typ: ResultTypeString, value: s} }
+
+// NewListResult creates a list Result. It is represented as its comma-separated elements in text.
+func NewListResult(rs []Result) Result { return Result{typ: ResultTypeList, value: rs} }
+
+// RecordField is a named field of a record Result.
+type RecordField struct {
+	Name  string
+	Value Result
+}
+
+// NewRecordResult creates a record Result, i.e. a Result with named fields, e.g. an item of a build order. It is
+// represented as its space-separated field values in text, and as an object in JSON.
+func NewRecordResult(fields []RecordField) Result {
+	return Result{typ: ResultTypeRecord, value: fields}
+}
+
+// Type returns the ResultType of this Result.
+func (r Result) Type() ResultType { return r.typ }
+
+// IsNull returns true if this Result is absent.
+func (r Result) IsNull() bool { return r.typ == ResultTypeNull }
+
+// IsTrue returns true if this Result is a true bool. Used for filters.
+func (r Result) IsTrue() bool { return r.typ == ResultTypeBool && r.value.(bool) }
+
+// Bool returns the value of a bool Result, or false otherwise.
+func (r Result) Bool() bool { return r.IsTrue() }
+
+// Int returns the value of an int Result, or 0 otherwise.
+func (r Result) Int() int {
+	if r.typ != ResultTypeInt {
+		return 0
+	}
+	return r.value.(int)
+}
+
+// Float returns the value of a float Result, or 0 otherwise.
+func (r Result) Float() float64 {
+	if r.typ != ResultTypeFloat {
+		return 0
+	}
+	return r.value.(float64)
+}
+
+// Duration returns the value of a duration Result, or 0 otherwise.
+func (r Result) Duration() time.Duration {
+	if r.typ != ResultTypeDuration {
+		return 0
+	}
+	return r.value.(time.Duration)
+}
+
+// Timestamp returns the value of a timestamp Result, or the zero time otherwise.
+func (r Result) Timestamp() time.Time {
+	if r.typ != ResultTypeTimestamp {
+		return time.Time{}
+	}
+	return r.value.(time.Time)
+}
+
+// List returns the elements of a list Result, or nil otherwise.
+func (r Result) List() []Result {
+	if r.typ != ResultTypeList {
+		return nil
+	}
+	return r.value.([]Result)
+}
+
+// Record returns the fields of a record Result, or nil otherwise.
+func (r Result) Record() []RecordField {
+	if r.typ != ResultTypeRecord {
+		return nil
+	}
+	return r.value.([]RecordField)
+}
+
+// recordField returns the value of the named field of a record Result, or a null Result if there's no such field.
+func recordField(r Result, name string) Result {
+	for _, field := range r.Record() {
+		if field.Name == name {
+			return field.Value
+		}
+	}
+	return NewNullResult()
+}
+
+// String returns the textual representation of this Result, as used in CSV output. Null Results are empty.
+func (r Result) String() string {
+	switch r.typ {
+	case ResultTypeBool:
+		return strconv.FormatBool(r.value.(bool))
+	case ResultTypeInt:
+		return strconv.Itoa(r.value.(int))
+	case ResultTypeFloat:
+		return strconv.FormatFloat(r.value.(float64), 'f', -1, 64)
+	case ResultTypeDuration:
+		seconds := int(r.value.(time.Duration).Seconds())
+		return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+	case ResultTypeTimestamp:
+		return r.value.(time.Time).Format(r.layout)
+	case ResultTypeString:
+		return r.value.(string)
+	case ResultTypeList:
+		ss := make([]string, len(r.value.([]Result)))
+		for i, e := range r.value.([]Result) {
+			ss[i] = e.String()
+		}
+		return strings.Join(ss, ",")
+	case ResultTypeRecord:
+		ss := make([]string, len(r.value.([]RecordField)))
+		for i, field := range r.value.([]RecordField) {
+			ss[i] = field.Value.String()
+		}
+		return strings.Join(ss, " ")
+	}
+	return ""
+}
+
+// MarshalJSON marshals this Result into its native JSON type. Durations are marshalled as a number of seconds.
+func (r Result) MarshalJSON() ([]byte, error) {
+	switch r.typ {
+	case ResultTypeBool, ResultTypeInt, ResultTypeString, ResultTypeList:
+		return json.Marshal(r.value)
+	case ResultTypeFloat:
+		if f := r.value.(float64); math.IsNaN(f) || math.IsInf(f, 0) {
+			return []byte("null"), nil // N.B. JSON has no NaN nor infinities, e.g. of a 0/0 ratio
+		}
+		return json.Marshal(r.value.(float64))
+	case ResultTypeDuration:
+		return json.Marshal(int(r.value.(time.Duration).Seconds()))
+	case ResultTypeTimestamp:
+		return json.Marshal(r.String())
+	case ResultTypeRecord: // N.B. not a map, to keep the order of fields
+		bs := []byte("{")
+		for i, field := range r.value.([]RecordField) {
+			name, err := json.Marshal(field.Name)
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				bs = append(bs, ',')
+			}
+			bs = append(append(append(bs, name...), ':'), value...)
+		}
+		return append(bs, '}'), nil
+	}
+	return []byte("null"), nil
+}
+
+// typedResult is the lossless JSON encoding of a Result, e.g. for caching.
+type typedResult struct {
+	Type   ResultType      `json:"type"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Layout string          `json:"layout,omitempty"`
+	List   []typedResult   `json:"list,omitempty"`
+	Names  []string        `json:"names,omitempty"` // of record fields, whose values are in List
+}
+
+func (r Result) toTyped() (typedResult, error) {
+	t := typedResult{Type: r.typ, Layout: r.layout}
+	var (
+		bs  []byte
+		err error
+	)
+	switch r.typ {
+	case ResultTypeNull:
+		return t, nil
+	case ResultTypeList:
+		for _, e := range r.List() {
+			te, err := e.toTyped()
+			if err != nil {
+				return t, err
+			}
+			t.List = append(t.List, te)
+		}
+		return t, nil
+	case ResultTypeRecord:
+		for _, field := range r.Record() {
+			te, err := field.Value.toTyped()
+			if err != nil {
+				return t, err
+			}
+			t.Names = append(t.Names, field.Name)
+			t.List = append(t.List, te)
+		}
+		return t, nil
+	case ResultTypeFloat:
+		if f := r.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			bs, err = json.Marshal(strconv.FormatFloat(f, 'f', -1, 64)) // N.B. JSON has no NaN nor infinities
+		} else {
+			bs, err = json.Marshal(f)
+		}
+	case ResultTypeDuration:
+		bs, err = json.Marshal(int64(r.Duration()))
+	case ResultTypeTimestamp:
+		bs, err = json.Marshal(r.Timestamp())
+	default:
+		bs, err = json.Marshal(r.value)
+	}
+	t.Value = bs
+	return t, err
+}
+
+func (t typedResult) toResult() (Result, error) {
+	var err error
+	switch t.Type {
+	case ResultTypeNull:
+		return NewNullResult(), nil
+	case ResultTypeBool:
+		var b bool
+		err = json.Unmarshal(t.Value, &b)
+		return NewBoolResult(b), err
+	case ResultTypeInt:
+		var i int
+		err = json.Unmarshal(t.Value, &i)
+		return NewIntResult(i), err
+	case ResultTypeFloat:
+		var f float64
+		if err = json.Unmarshal(t.Value, &f); err != nil { // N.B. NaN and infinities are strings
+			var s string
+			if json.Unmarshal(t.Value, &s) == nil {
+				f, err = strconv.ParseFloat(s, 64)
+			}
+		}
+		return NewFloatResult(f), err
+	case ResultTypeDuration:
+		var d int64
+		err = json.Unmarshal(t.Value, &d)
+		return NewDurationResult(time.Duration(d)), err
+	case ResultTypeTimestamp:
+		var ts time.Time
+		err = json.Unmarshal(t.Value, &ts)
+		return NewTimestampResult(ts, t.Layout), err
+	case ResultTypeString:
+		var s string
+		err = json.Unmarshal(t.Value, &s)
+		return NewStringResult(s), err
+	case ResultTypeList:
+		rs := make([]Result, len(t.List))
+		for i, te := range t.List {
+			if rs[i], err = te.toResult(); err != nil {
+				return NewNullResult(), err
+			}
+		}
+		return NewListResult(rs), nil
+	case ResultTypeRecord:
+		if len(t.Names) != len(t.List) {
+			return NewNullResult(), fmt.Errorf("record has %v names but %v values", len(t.Names), len(t.List))
+		}
+		fields := make([]RecordField, len(t.List))
+		for i, te := range t.List {
+			fields[i].Name = t.Names[i]
+			if fields[i].Value, err = te.toResult(); err != nil {
+				return NewNullResult(), err
+			}
+		}
+		return NewRecordResult(fields), nil
+	}
+	return NewNullResult(), fmt.Errorf("unknown result type %v", t.Type)
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"math"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// find1v1StartLocationTiles returns the start locations in tiles of the two players of a 1v1, i.e. a replay with
+// exactly two players (ignoring observers), in team order. Returns false otherwise, or if they have no start
+// locations (e.g. on UMS maps).
+func find1v1StartLocationTiles(replay *rep.Replay) (repcore.Point, repcore.Point, bool) {
+	playerIDs := findPlayingPlayerIDs(replay)
+	if len(playerIDs) != 2 {
+		return repcore.Point{}, repcore.Point{}, false
+	}
+	a, okA := findStartLocationTile(replay, playerIDs[0])
+	b, okB := findStartLocationTile(replay, playerIDs[1])
+	return a, b, okA && okB
+}
+
+// spawnType classifies the relative position of two start locations on a map of the given size in tiles:
+//
+//   - "cross" if they are on opposite corners, i.e. at least half the map apart both horizontally and vertically,
+//   - "close-by-air" if they are less than half the map apart both horizontally and vertically,
+//   - otherwise "vertical" or "horizontal", depending on the axis along which they are farther apart.
+func spawnType(a, b repcore.Point, width, height uint16) string {
+	var (
+		dx = math.Abs(float64(a.X)-float64(b.X)) / float64(width)
+		dy = math.Abs(float64(a.Y)-float64(b.Y)) / float64(height)
+	)
+	switch {
+	case dx >= 0.5 && dy >= 0.5:
+		return "cross"
+	case dx < 0.5 && dy < 0.5:
+		return "close-by-air"
+	case dy >= dx:
+		return "vertical"
+	}
+	return "horizontal"
+}
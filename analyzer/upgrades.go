@@ -0,0 +1,46 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+// upgradeRepeatWindow is how soon an UpgradeCmd of the same upgrade is considered repeated (e.g. spam, or retrying
+// without enough resources) rather than the next level of the upgrade.
+const upgradeRepeatWindow = time.Minute
+
+// upgradeList accumulates the upgrades a player started, in order. An upgrade appears once per level.
+type upgradeList struct {
+	playerID   byte
+	names      []string
+	lastFrames map[string]repcore.Frame
+}
+
+func newUpgradeList(playerID byte) *upgradeList {
+	return &upgradeList{playerID: playerID, lastFrames: map[string]repcore.Frame{}}
+}
+
+// add adds the command if it's an UpgradeCmd of the player that is not repeated. Returns true if it was.
+func (l *upgradeList) add(command repcmd.Cmd) bool {
+	c, ok := command.(*repcmd.UpgradeCmd)
+	if !ok || c.PlayerID != l.playerID {
+		return false
+	}
+	lastFrame, ok := l.lastFrames[c.Upgrade.Name]
+	l.lastFrames[c.Upgrade.Name] = c.Frame
+	if ok && c.Frame.Duration()-lastFrame.Duration() < upgradeRepeatWindow {
+		return false
+	}
+	l.names = append(l.names, c.Upgrade.Name)
+	return true
+}
+
+func (l *upgradeList) results() []Result {
+	results := make([]Result, len(l.names))
+	for i, name := range l.names {
+		results[i] = NewStringResult(name)
+	}
+	return results
+}
@@ -4,41 +4,193 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/icza/screp/rep"
 	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
 )
 
-func findPlayerID(replay *rep.Replay, names map[string]struct{}) byte {
+// findPlayerID returns the ID of the -me player, or of the player whose row is being analyzed in per-player mode.
+func findPlayerID(replay *rep.Replay, ctx Context) byte {
+	if ctx.player != nil {
+		return ctx.player.ID
+	}
 	for _, p := range replay.Header.PIDPlayers {
-		if _, ok := names[p.Name]; ok {
+		if _, ok := ctx.Me[p.Name]; ok {
 			return p.ID
 		}
 	}
 	return 127 // On a byte field and for a player id, this will be a poor man's None
 }
 
+// findOpponentIDs returns the IDs of the opponents of the -me player (see findPlayerID), in team order: players on
+// other teams, or every other player on game types without teams (e.g. on Melee every player is on Team 0).
+func findOpponentIDs(replay *rep.Replay, ctx Context) []byte {
+	playerID := findPlayerID(replay, ctx)
+	if playerID == 127 {
+		return nil
+	}
+	var (
+		me          = replay.Header.PIDPlayers[playerID]
+		ignoreTeams = isGameTypeWithoutTeams(replay.Header.Type)
+		opponentIDs = []byte{}
+	)
+	for _, p := range replay.Header.Players {
+		if p.ID != playerID && !isObserver(p) && (ignoreTeams || p.Team != me.Team) {
+			opponentIDs = append(opponentIDs, p.ID)
+		}
+	}
+	return opponentIDs
+}
+
+// findOpponentID returns the ID of the only opponent of the -me player (e.g. on 1v1s), or 127 if there isn't
+// exactly one.
+func findOpponentID(replay *rep.Replay, ctx Context) byte {
+	opponentIDs := findOpponentIDs(replay, ctx)
+	if len(opponentIDs) != 1 {
+		return 127
+	}
+	return opponentIDs[0]
+}
+
+// isGameTypeWithoutTeams returns true on game types where all players are on the same team in the replay header,
+// but every player plays for themselves.
+func isGameTypeWithoutTeams(gameType *repcore.GameType) bool {
+	switch gameType {
+	case repcore.GameTypeMelee, repcore.GameTypeFFA, repcore.GameType1v1, repcore.GameTypeLadder, repcore.GameTypeGreed,
+		repcore.GameTypeSlaughter, repcore.GameTypeSuddenDeath:
+		return true
+	}
+	return false
+}
+
+// isObserver returns true if the player is observing rather than playing. Only the 8 playing slots have a color.
+func isObserver(p *rep.Player) bool {
+	return p.Color == nil
+}
+
+// findPlayingPlayerIDs returns the IDs of the players of the replay that are not observers, in team order.
+func findPlayingPlayerIDs(replay *rep.Replay) []byte {
+	playerIDs := []byte{}
+	for _, p := range replay.Header.Players {
+		if !isObserver(p) {
+			playerIDs = append(playerIDs, p.ID)
+		}
+	}
+	return playerIDs
+}
+
+// teamFormat returns the team format of the replay, e.g. "1v1", "2v2", "3v1", "2v2v2v2" or "FFA4", with the
+// teams from largest to smallest. Observers and computers are ignored. Returns false if there are no players.
+func teamFormat(replay *rep.Replay) (string, bool) {
+	teamSizes := map[byte]int{}
+	players := 0
+	for _, p := range replay.Header.Players {
+		if isObserver(p) || p.Type != repcore.PlayerTypeHuman {
+			continue
+		}
+		teamSizes[p.Team]++
+		players++
+	}
+	switch {
+	case players == 0:
+		return "", false
+	case isGameTypeWithoutTeams(replay.Header.Type) && players <= 2:
+		return strings.Repeat("1v", players-1) + "1", true
+	case isGameTypeWithoutTeams(replay.Header.Type):
+		return fmt.Sprintf("FFA%v", players), true
+	}
+	sizes := make([]int, 0, len(teamSizes))
+	for _, size := range teamSizes {
+		sizes = append(sizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	ss := make([]string, len(sizes))
+	for i, size := range sizes {
+		ss[i] = strconv.Itoa(size)
+	}
+	return strings.Join(ss, "v"), true
+}
+
+// findPlayerDesc returns screp's computed data for the given player, or nil if there's none.
+func findPlayerDesc(replay *rep.Replay, playerID byte) *rep.PlayerDesc {
+	if replay.Computed == nil {
+		return nil
+	}
+	for _, pDesc := range replay.Computed.PlayerDescs {
+		if pDesc.PlayerID == playerID {
+			return pDesc
+		}
+	}
+	return nil
+}
+
+// pixelsToTile converts a map position in pixels (e.g. start locations) into map tiles (e.g. BuildCmd positions).
+func pixelsToTile(p repcore.Point) repcore.Point {
+	return repcore.Point{X: p.X / 32, Y: p.Y / 32}
+}
+
 // If the command is a specific building/unit creation/evolution of a specific player id, it returns the second
 // that it happened. Returns true if it was.
 // Should be used on ProcessCommand.
-func maybePlayersUnitSeconds(command repcmd.Cmd, playerID byte, unitID uint16) (string, bool) {
+func maybePlayersUnitSeconds(command repcmd.Cmd, playerID byte, unitID uint16) (int, bool) {
 	if command.BaseCmd().PlayerID == playerID {
 		switch c := command.(type) {
 		case *repcmd.BuildCmd: // N.B due to a limitation in Go's type system this code cannot be simpler -_-
 			if c.Unit.ID == unitID {
-				return fmt.Sprintf("%v", int(c.Frame.Seconds())), true
+				return int(c.Frame.Seconds()), true
 			}
 		case *repcmd.BuildingMorphCmd:
 			if c.Unit.ID == unitID {
-				return fmt.Sprintf("%v", int(c.Frame.Seconds())), true
+				return int(c.Frame.Seconds()), true
 			}
 		case *repcmd.TrainCmd:
 			if c.Unit.ID == unitID {
-				return fmt.Sprintf("%v", int(c.Frame.Seconds())), true
+				return int(c.Frame.Seconds()), true
 			}
 		}
 	}
-	return "-1", false
+	return -1, false
+}
+
+// findEnumName finds the name among the given ones, case-insensitively and optionally omitting the description in
+// parentheses, e.g. "metabolic boost" is "Metabolic Boost (Zergling Speed)".
+func findEnumName(name string, names []string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, n := range names {
+		if strings.ToLower(n) == name {
+			return n, true
+		}
+	}
+	for _, n := range names {
+		if i := strings.Index(n, " ("); i >= 0 && strings.ToLower(n[:i]) == name {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// techNames returns the names of all techs, except unused ones.
+func techNames() []string {
+	names := []string{}
+	for _, tech := range repcmd.Techs {
+		if !strings.HasPrefix(tech.Name, "Unused") {
+			names = append(names, tech.Name)
+		}
+	}
+	return names
+}
+
+// upgradeNames returns the names of all upgrades.
+func upgradeNames() []string {
+	names := make([]string, len(repcmd.Upgrades))
+	for i, upgrade := range repcmd.Upgrades {
+		names[i] = upgrade.Name
+	}
+	return names
 }
 
 var (
@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// whereExpression is a compiled -where expression: a boolean expression over Analyzer results, e.g.
+//
+//	my-matchup-is(TvZ) or (my-matchup-is(TvP) and not duration-minutes-is-lower-than(10))
+//
+// Its atoms are analyzers with boolean results, which the Executor runs as hidden filters. It is evaluated with
+// three-valued logic, so that a replay can be excluded as soon as the expression is known to be false, even if
+// some atoms are not done yet.
+type whereExpression interface {
+	// eval returns the value of the expression, and false if it cannot be known yet.
+	eval(analysis *replayAnalysis) (value bool, known bool)
+}
+
+type whereAtom struct {
+	analyzerRequest []string
+	pos             int // of the analyzerWrapper that computes it; set once analyzerWrappers are sorted
+}
+
+func (a *whereAtom) eval(analysis *replayAnalysis) (bool, bool) {
+	return analysis.analyzerWrappers[a.pos].matches(analysis.results[a.pos]), analysis.done[a.pos]
+}
+
+type whereNot struct{ operand whereExpression }
+
+func (n whereNot) eval(analysis *replayAnalysis) (bool, bool) {
+	value, known := n.operand.eval(analysis)
+	return !value, known
+}
+
+type whereAnd struct{ operands []whereExpression }
+
+func (a whereAnd) eval(analysis *replayAnalysis) (bool, bool) {
+	allKnown := true
+	for _, operand := range a.operands {
+		value, known := operand.eval(analysis)
+		if known && !value {
+			return false, true
+		}
+		allKnown = allKnown && known
+	}
+	return true, allKnown
+}
+
+type whereOr struct{ operands []whereExpression }
+
+func (o whereOr) eval(analysis *replayAnalysis) (bool, bool) {
+	allKnown := true
+	for _, operand := range o.operands {
+		value, known := operand.eval(analysis)
+		if known && value {
+			return true, true
+		}
+		allKnown = allKnown && known
+	}
+	return false, allKnown
+}
+
+// parseWhere parses a -where expression. It returns the expression and its atoms, whose analyzer requests have the
+// same shape as NewExecutor's analyzerRequests. Grammar (keywords are case-insensitive):
+//
+//	expression := and-expression { "or" and-expression }
+//	and-expression := not-expression { "and" not-expression }
+//	not-expression := "not" not-expression | "(" expression ")" | atom
+//	atom := analyzer-name [ "(" comma-separated arguments ")" ]
+func parseWhere(s string) (whereExpression, []*whereAtom, error) {
+	p := &whereParser{s: s}
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpaces()
+	if p.i < len(p.s) {
+		return nil, nil, fmt.Errorf("unexpected %q at position %v", p.s[p.i:], p.i)
+	}
+	return expression, p.atoms, nil
+}
+
+type whereParser struct {
+	s     string
+	i     int
+	atoms []*whereAtom
+}
+
+func (p *whereParser) parseOr() (whereExpression, error) {
+	operands := []whereExpression{}
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return whereOr{operands}, nil
+}
+
+func (p *whereParser) parseAnd() (whereExpression, error) {
+	operands := []whereExpression{}
+	for {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return whereAnd{operands}, nil
+}
+
+func (p *whereParser) parseNot() (whereExpression, error) {
+	if p.keyword("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return whereNot{operand}, nil
+	}
+	p.skipSpaces()
+	if p.i < len(p.s) && p.s[p.i] == '(' {
+		p.i++
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.i >= len(p.s) || p.s[p.i] != ')' {
+			return nil, fmt.Errorf("expected ')' at position %v", p.i)
+		}
+		p.i++
+		return expression, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *whereParser) parseAtom() (whereExpression, error) {
+	p.skipSpaces()
+	start := p.i
+	for p.i < len(p.s) && isWhereNameChar(p.s[p.i]) {
+		p.i++
+	}
+	if start == p.i {
+		if p.i >= len(p.s) {
+			return nil, fmt.Errorf("expected analyzer name at the end")
+		}
+		return nil, fmt.Errorf("expected analyzer name at position %v", p.i)
+	}
+	atom := &whereAtom{analyzerRequest: []string{p.s[start:p.i]}}
+	p.skipSpaces()
+	if p.i < len(p.s) && p.s[p.i] == '(' {
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		atom.analyzerRequest = append(atom.analyzerRequest, args...)
+	}
+	p.atoms = append(p.atoms, atom)
+	return atom, nil
+}
+
+// parseArguments parses a parenthesized, comma-separated list of arguments. Arguments may contain balanced
+// parentheses, e.g. my-first-specific-unit-seconds(Siege Tank (Tank Mode)).
+func (p *whereParser) parseArguments() ([]string, error) {
+	var (
+		start = p.i + 1
+		depth = 0
+	)
+	for ; p.i < len(p.s); p.i++ {
+		switch p.s[p.i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("expected ')' closing arguments at position %v", start-1)
+	}
+	args := []string{}
+	for _, arg := range strings.Split(p.s[start:p.i], ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+	p.i++
+	return args, nil
+}
+
+// keyword consumes the given keyword if it's next, as a whole word.
+func (p *whereParser) keyword(keyword string) bool {
+	p.skipSpaces()
+	end := p.i + len(keyword)
+	if end > len(p.s) || !strings.EqualFold(p.s[p.i:end], keyword) || (end < len(p.s) && isWhereNameChar(p.s[end])) {
+		return false
+	}
+	p.i = end
+	return true
+}
+
+func (p *whereParser) skipSpaces() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t' || p.s[p.i] == '\n') {
+		p.i++
+	}
+}
+
+func isWhereNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-'
+}
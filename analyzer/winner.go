@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/icza/screp/rep"
+	"github.com/icza/screp/rep/repcmd"
+)
+
+// Signals that can decide the winner of a game, from most to least reliable (see findWinners).
+const (
+	winnerSignalTeam        = "team"         // screp's "largest remaining team wins"
+	winnerSignalLeaveReason = "leave-reason" // a player left with Defeat or Victory
+	winnerSignalLeaveOrder  = "leave-order"  // the first player to leave loses
+	winnerSignalGG          = "gg"           // the first player to say "good game" loses
+	winnerSignalLastCommand = "last-command" // the player who kept playing the longest wins
+)
+
+// findLeaves returns the LeaveGameCmds of the players of the replay that are not observers, in order.
+func findLeaves(replay *rep.Replay) []*repcmd.LeaveGameCmd {
+	leaves := []*repcmd.LeaveGameCmd{}
+	for _, c := range replay.Computed.LeaveGameCmds {
+		if p, ok := replay.Header.PIDPlayers[c.PlayerID]; ok && !isObserver(p) {
+			leaves = append(leaves, c)
+		}
+	}
+	return leaves
+}
+
+// findLeave returns the LeaveGameCmd of the player, or nil if the player didn't leave (e.g. usually the player who
+// saved the replay).
+func findLeave(replay *rep.Replay, playerID byte) *repcmd.LeaveGameCmd {
+	for _, c := range replay.Computed.LeaveGameCmds {
+		if c.PlayerID == playerID {
+			return c
+		}
+	}
+	return nil
+}
+
+// leavesResults returns the leaves as a list of records with the player's name, the time (as a duration), the
+// frame and the reason of each leave.
+func leavesResults(replay *rep.Replay, leaves []*repcmd.LeaveGameCmd) []Result {
+	results := make([]Result, len(leaves))
+	for i, c := range leaves {
+		results[i] = NewRecordResult([]RecordField{
+			{Name: "player", Value: NewStringResult(replay.Header.PIDPlayers[c.PlayerID].Name)},
+			{Name: "time", Value: NewDurationResult(c.Frame.Duration().Truncate(time.Second))},
+			{Name: "frame", Value: NewIntResult(int(c.Frame))},
+			{Name: "reason", Value: NewStringResult(leaveReasonName(c))},
+		})
+	}
+	return results
+}
+
+// findWinners returns the IDs of the players who won the game, and the signal that decided it, trying in order:
+//
+//   - screp's computed winner team, if known,
+//   - a player who left with Defeat (the player's team loses) or Victory (the player's team wins),
+//   - the first player to leave (the player's team loses),
+//   - the first player to say "good game" (see ggRegexp; the player's team loses),
+//   - the team whose players' last command was the latest (wins).
+//
+// All but the first signal only decide games between two teams. Returns false if no signal decides.
+func findWinners(replay *rep.Replay) ([]byte, string, bool) {
+	if replay.Computed == nil {
+		return nil, "", false
+	}
+	if replay.Computed.WinnerTeam != 0 {
+		winners := []byte{}
+		for _, playerID := range findPlayingPlayerIDs(replay) {
+			if replay.Header.PIDPlayers[playerID].Team == replay.Computed.WinnerTeam {
+				winners = append(winners, playerID)
+			}
+		}
+		return winners, winnerSignalTeam, true
+	}
+	teams := findPlayingTeams(replay)
+	if len(teamSet(teams)) != 2 {
+		return nil, "", false
+	}
+	// playersOnTeam returns the players who are (or aren't) on the player's team.
+	playersOnTeam := func(playerID byte, isSame bool) []byte {
+		players := []byte{}
+		for _, id := range findPlayingPlayerIDs(replay) {
+			if (teams[id] == teams[playerID]) == isSame {
+				players = append(players, id)
+			}
+		}
+		return players
+	}
+	leaves := findLeaves(replay)
+	for _, c := range leaves {
+		switch leaveReasonName(c) {
+		case "Defeat":
+			return playersOnTeam(c.PlayerID, false), winnerSignalLeaveReason, true
+		case "Victory":
+			return playersOnTeam(c.PlayerID, true), winnerSignalLeaveReason, true
+		}
+	}
+	if len(leaves) > 0 {
+		return playersOnTeam(leaves[0].PlayerID, false), winnerSignalLeaveOrder, true
+	}
+	for _, c := range replay.Computed.ChatCmds {
+		if _, ok := teams[c.PlayerID]; ok && ggRegexp.MatchString(c.Message) {
+			return playersOnTeam(c.PlayerID, false), winnerSignalGG, true
+		}
+	}
+	var (
+		lastPlayerID byte
+		lastFrame    = -1
+		isTie        bool
+	)
+	for _, playerID := range findPlayingPlayerIDs(replay) {
+		pDesc := findPlayerDesc(replay, playerID)
+		if pDesc == nil {
+			continue
+		}
+		switch frame := int(pDesc.LastCmdFrame); {
+		case frame > lastFrame:
+			lastPlayerID, lastFrame, isTie = playerID, frame, false
+		case frame == lastFrame && teams[playerID] != teams[lastPlayerID]:
+			isTie = true
+		}
+	}
+	if lastFrame == -1 || isTie {
+		return nil, "", false
+	}
+	return playersOnTeam(lastPlayerID, true), winnerSignalLastCommand, true
+}
+
+// findPlayingTeams returns the team of each player of the replay that is not an observer. On game types without
+// teams (see isGameTypeWithoutTeams), every player is on their own team.
+func findPlayingTeams(replay *rep.Replay) map[byte]byte {
+	teams := map[byte]byte{}
+	for i, p := range replay.Header.Players {
+		if isObserver(p) {
+			continue
+		}
+		teams[p.ID] = p.Team
+		if isGameTypeWithoutTeams(replay.Header.Type) {
+			teams[p.ID] = byte(i)
+		}
+	}
+	return teams
+}
+
+func teamSet(teams map[byte]byte) map[byte]struct{} {
+	set := map[byte]struct{}{}
+	for _, team := range teams {
+		set[team] = struct{}{}
+	}
+	return set
+}
+
+func leaveReasonName(c *repcmd.LeaveGameCmd) string {
+	if c.Reason == nil {
+		return "Unknown"
+	}
+	return c.Reason.Name
+}
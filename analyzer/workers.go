@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/icza/screp/rep/repcmd"
+	"github.com/icza/screp/rep/repcore"
+)
+
+const (
+	startingWorkers     = 4    // on melee games
+	workerBuildFrames   = 300  // for SCVs, Probes and Drones alike
+	townHallBuildFrames = 1800 // for Command Centers and Nexuses alike
+	productionQueueSize = 5
+)
+
+// workerTimeline accumulates the frames at which a player ordered workers (i.e. SCVs, Probes and Drones), and at
+// which they were produced. Since players often spam worker orders onto full production queues, SCV and Probe orders
+// are only accepted if a town hall of the player would have had room in its queue, and they are produced
+// workerBuildFrames after the town hall is done with the previous ones; Drones are accepted as ordered, and produced
+// workerBuildFrames later. Since workers that die (or orders that are canceled) are unknown, worker counts are
+// estimates.
+type workerTimeline struct {
+	playerID       byte
+	frames         []repcore.Frame // of the accepted orders
+	producedFrames []repcore.Frame
+	townHalls      []repcore.Frame // frame each of the player's town halls finishes its queue (or being built)
+	placed         map[buildingPlacement]struct{}
+}
+
+func newWorkerTimeline(playerID byte) *workerTimeline {
+	return &workerTimeline{playerID: playerID, townHalls: []repcore.Frame{0}, placed: map[buildingPlacement]struct{}{}}
+}
+
+// add adds the command if it's an accepted worker TrainCmd of the player. Returns true if it was.
+func (w *workerTimeline) add(command repcmd.Cmd) bool {
+	if command.BaseCmd().PlayerID != w.playerID {
+		return false
+	}
+	switch c := command.(type) {
+	case *repcmd.BuildCmd:
+		placement := buildingPlacement{c.Unit.ID, c.Pos}
+		if _, ok := w.placed[placement]; ok {
+			return false
+		}
+		w.placed[placement] = struct{}{}
+		if c.Unit.ID == nameToUnitID["Command Center"] || c.Unit.ID == nameToUnitID["Nexus"] {
+			w.townHalls = append(w.townHalls, c.Frame+townHallBuildFrames)
+		}
+	case *repcmd.TrainCmd:
+		if !isWorker(c.Unit.ID) {
+			return false
+		}
+		produced := c.Frame + workerBuildFrames
+		if c.Unit.ID != nameToUnitID["Drone"] {
+			var ok bool
+			if produced, ok = w.enqueue(c.Frame); !ok {
+				return false
+			}
+		}
+		w.frames = append(w.frames, c.Frame)
+		w.producedFrames = append(w.producedFrames, produced)
+		return true
+	}
+	return false
+}
+
+// enqueue queues a worker on the town hall that finishes its queue first, if there's room in its queue, and returns
+// the frame the worker is produced.
+func (w *workerTimeline) enqueue(frame repcore.Frame) (repcore.Frame, bool) {
+	first := 0
+	for i, finish := range w.townHalls {
+		if finish < w.townHalls[first] {
+			first = i
+		}
+	}
+	start := w.townHalls[first]
+	if start < frame {
+		start = frame
+	}
+	if start-frame >= productionQueueSize*workerBuildFrames {
+		return 0, false
+	}
+	w.townHalls[first] = start + workerBuildFrames
+	return w.townHalls[first], true
+}
+
+// perMinute returns the number of workers ordered in each minute of the game, until the last one.
+func (w *workerTimeline) perMinute() []Result {
+	return framesPerMinute(w.frames, -1)
+}
+
+// producedPerMinute returns the number of workers produced in each minute of the game, until the last one. Workers
+// that wouldn't be produced until after the given end of the game are not counted, since they never were.
+func (w *workerTimeline) producedPerMinute(end time.Duration) []Result {
+	return framesPerMinute(w.producedFrames, end)
+}
+
+// framesPerMinute returns the number of frames in each minute of the game, until the last one, ignoring the frames
+// after the given end (if not negative).
+func framesPerMinute(frames []repcore.Frame, end time.Duration) []Result {
+	perMinute := []int{}
+	for _, frame := range frames {
+		if end >= 0 && frame.Duration() > end {
+			continue
+		}
+		minute := int(frame.Duration() / time.Minute)
+		for len(perMinute) <= minute {
+			perMinute = append(perMinute, 0)
+		}
+		perMinute[minute]++
+	}
+	return perMinuteResults(perMinute)
+}
+
+// longestGap returns the longest time without ordering workers from the beginning of the game until the given
+// time.
+func (w *workerTimeline) longestGap(until time.Duration) time.Duration {
+	var (
+		longest time.Duration
+		prev    time.Duration
+	)
+	for _, frame := range w.frames {
+		if frame.Duration() > until {
+			break
+		}
+		if gap := frame.Duration() - prev; gap > longest {
+			longest = gap
+		}
+		prev = frame.Duration()
+	}
+	if gap := until - prev; gap > longest {
+		longest = gap
+	}
+	return longest
+}
+
+func isWorker(unitID uint16) bool {
+	return unitID == nameToUnitID["SCV"] || unitID == nameToUnitID["Probe"] || unitID == nameToUnitID["Drone"]
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/marianogappa/sctool/analyzer"
@@ -36,15 +38,15 @@ func main() {
 }
 
 func buildAnalyzerExecutor(args []string) (*analyzer.Executor, bool, []error) {
-	fs, stringFlags, boolFlags, fHelp, fOutput, fQuiet, fCopyToIfMatchesFilters := newFlagSet()
-	fs.Parse(args)
-	if *fHelp {
+	f := newFlagSet()
+	f.fs.Parse(args)
+	if *f.help {
 		flag.Usage()
 		os.Exit(0)
 	}
 
 	var output analyzer.Output
-	switch *fOutput {
+	switch *f.output {
 	case "json":
 		output = analyzer.NewJSONOutput(os.Stdout)
 	case "none":
@@ -53,61 +55,150 @@ func buildAnalyzerExecutor(args []string) (*analyzer.Executor, bool, []error) {
 		output = analyzer.NewCSVOutput(os.Stdout)
 	}
 
+	analyzerRequests := resolveAnalyzerRequests(f.stringFlags, f.boolFlags)
+	if *f.groupBy != "" || *f.aggregate != "" {
+		aggregateOutput, err := analyzer.NewAggregateOutput(output, *f.groupBy, *f.aggregate)
+		if err != nil {
+			return nil, *f.quiet, []error{err}
+		}
+		output = aggregateOutput
+		analyzerRequests = appendMissingAnalyzerRequests(analyzerRequests, aggregateOutput.AnalyzerRequests())
+	}
+
+	ctx, err := resolveContext(f.fs)
+	if err != nil {
+		return nil, *f.quiet, []error{err}
+	}
 	executor, errs := analyzer.NewExecutor(
-		resolveReplayPaths(fs),
-		resolveAnalyzerRequests(stringFlags, boolFlags),
-		resolveContext(fs),
+		resolveReplayPaths(f.fs),
+		analyzerRequests,
+		ctx,
 		output,
-		*fCopyToIfMatchesFilters,
+		*f.copyToIfMatchesFilters,
+		analyzer.WithWorkers(*f.workers),
+		analyzer.WithCacheDir(*f.cacheDir),
+		analyzer.WithWhere(*f.where),
+		analyzer.WithPerPlayer(*f.perPlayer),
 	)
-	return executor, *fQuiet, errs
+	return executor, *f.quiet, errs
 }
 
-func newFlagSet() (*flag.FlagSet, map[string]*string, map[string]*bool, *bool, *string, *bool, *string) {
-	var (
-		fs                      = flag.NewFlagSet("", flag.ExitOnError)
-		stringFlags             = map[string]*string{}
-		boolFlags               = map[string]*bool{}
-		fOutput                 = fs.String("o", "csv", "output format {csv|json|none} default: csv")
-		fCopyToIfMatchesFilters = fs.String("copy-to-if-matches-filters", "",
-			"copy replay files matched by -filter-- and not matched by -filter--not-- filters to specified directory")
-		fQuiet = fs.Bool("quiet", false, "don't print any errors (discouraged: note that you can silence with 2>/dev/null).")
-		fHelp  = fs.Bool("help", false, "Returns help usage and exits.")
-	)
+// flags are the parsed command line flags. Flags that only build the Context or the replay paths are looked up on fs
+// instead (see resolveContext and resolveReplayPaths).
+type flags struct {
+	fs                     *flag.FlagSet
+	stringFlags            map[string]*string // of analyzers (and their filters) with arguments
+	boolFlags              map[string]*bool   // of analyzers (and their filters) without arguments
+	help                   *bool
+	output                 *string
+	quiet                  *bool
+	copyToIfMatchesFilters *string
+	workers                *int
+	cacheDir               *string
+	where                  *string
+	perPlayer              *bool
+	groupBy                *string
+	aggregate              *string
+}
+
+func newFlagSet() flags {
+	fs := flag.NewFlagSet("", flag.ExitOnError)
+	f := flags{
+		fs:          fs,
+		stringFlags: map[string]*string{},
+		boolFlags:   map[string]*bool{},
+		output:      fs.String("o", "csv", "output format {csv|json|none} default: csv"),
+		copyToIfMatchesFilters: fs.String("copy-to-if-matches-filters", "",
+			"copy replay files matched by -filter-- and -where and not matched by -filter--not-- filters to specified directory"),
+		quiet:     fs.Bool("quiet", false, "don't print any errors (discouraged: note that you can silence with 2>/dev/null)."),
+		help:      fs.Bool("help", false, "Returns help usage and exits."),
+		workers:   fs.Int("workers", runtime.GOMAXPROCS(0), "number of replays to parse and analyze concurrently (output order is unaffected)"),
+		cacheDir:  fs.String("cache-dir", "", "directory to cache analyzer results in, so that unchanged replays aren't parsed again on later runs"),
+		perPlayer: fs.Bool("per-player", false, "output a row per player per replay rather than per replay, in which -me is that player (e.g. -my-race is each player's race)"),
+		groupBy:   fs.String("group-by", "", "comma-separated analyzers to group results by, outputting a row per group with -aggregate columns, e.g. 'my-matchup,map-name'"),
+		aggregate: fs.String("aggregate", "", "comma-separated aggregations for each -group-by group: count, avg(analyzer), sum(analyzer), min(analyzer), max(analyzer), rate(analyzer), e.g. 'count,avg(my-apm),rate(my-inferred-win)' (default: count)"),
+		where:     fs.String("where", "", "only output replays matching this expression of true/false analyzers combined with and/or/not and parentheses, e.g. 'my-matchup-is(TvZ) or (my-matchup-is(TvP) and duration-minutes-is-greater-than(10))'"),
+	}
 	fs.String("replay", "", "(>= 1 replays required) path to replay file")
 	fs.String("replays", "", "(>= 1 replays required) comma-separated paths to replay files")
 	fs.String("replay-dir", "", "(>= 1 replays required) path to folder with replays (recursive)")
 	fs.String("me", "", "comma-separated list of player names to identify as the main player")
+	fs.String("openings", "", "path to a JSON file with the rules for -my-opening, to use instead of the default ones (see README)")
+	fs.String("maps", "", "path to a JSON file with the catalog of known maps for -canonical-map (see README)")
+	fs.String("map-name-rules", "", "path to a JSON file with the regular expressions whose matches -map-name-normalized removes from map names, to use instead of the default ones (see README)")
 	for name, a := range analyzer.Analyzers {
 		if a.IsStringFlag() {
-			stringFlags[name] = fs.String(name, "", a.Description())
+			f.stringFlags[name] = fs.String(name, "", a.Description())
 			if a.IsBooleanResult() {
-				stringFlags["filter--"+name] = fs.String("filter--"+name, "", "Filter for: "+a.Description())
-				stringFlags["filter-not--"+name] = fs.String("filter-not--"+name, "", "Filter-Not for: "+a.Description())
+				f.stringFlags["filter--"+name] = fs.String("filter--"+name, "", "Filter for: "+a.Description())
+				f.stringFlags["filter-not--"+name] = fs.String("filter-not--"+name, "", "Filter-Not for: "+a.Description())
 			}
 		} else {
-			boolFlags[name] = fs.Bool(name, false, a.Description())
+			f.boolFlags[name] = fs.Bool(name, false, a.Description())
 			if a.IsBooleanResult() {
-				boolFlags["filter--"+name] = fs.Bool("filter--"+name, false, "Filter for: "+a.Description())
-				boolFlags["filter-not--"+name] = fs.Bool("filter-not--"+name, false, "Filter-Not for: "+a.Description())
+				f.boolFlags["filter--"+name] = fs.Bool("filter--"+name, false, "Filter for: "+a.Description())
+				f.boolFlags["filter-not--"+name] = fs.Bool("filter-not--"+name, false, "Filter-Not for: "+a.Description())
 			}
 		}
+		if a.IsNumericResult() { // N.B. always string flags, since the last argument is a comparison e.g. '>=150'
+			f.stringFlags["filter--"+name] = fs.String("filter--"+name, "", "Filter (last argument is a comparison e.g. '>=150') for: "+a.Description())
+			f.stringFlags["filter-not--"+name] = fs.String("filter-not--"+name, "", "Filter-Not (last argument is a comparison e.g. '>=150') for: "+a.Description())
+		}
 	}
-	return fs, stringFlags, boolFlags, fHelp, fOutput, fQuiet, fCopyToIfMatchesFilters
+	return f
 }
 
-func resolveContext(fs *flag.FlagSet) analyzer.Context {
-	var fMe string
+func resolveContext(fs *flag.FlagSet) (analyzer.Context, error) {
+	var fMe, fOpenings, fMaps, fMapNameRules string
 	if fs.Lookup("me") != nil {
 		fMe = fs.Lookup("me").Value.String()
 	}
+	if fs.Lookup("openings") != nil {
+		fOpenings = fs.Lookup("openings").Value.String()
+	}
+	if fs.Lookup("maps") != nil {
+		fMaps = fs.Lookup("maps").Value.String()
+	}
+	if fs.Lookup("map-name-rules") != nil {
+		fMapNameRules = fs.Lookup("map-name-rules").Value.String()
+	}
 	ctx := analyzer.Context{Me: map[string]struct{}{}}
 	if len(fMe) > 0 {
 		for _, name := range strings.Split(fMe, ",") {
 			ctx.Me[strings.TrimSpace(name)] = struct{}{}
 		}
 	}
-	return ctx
+	if fOpenings != "" {
+		f, err := os.Open(fOpenings)
+		if err != nil {
+			return ctx, fmt.Errorf("couldn't open -openings file: %v", err)
+		}
+		defer f.Close()
+		if ctx.Openings, err = analyzer.ParseOpenings(f); err != nil {
+			return ctx, fmt.Errorf("invalid -openings file %v: %v", fOpenings, err)
+		}
+	}
+	if fMaps != "" {
+		f, err := os.Open(fMaps)
+		if err != nil {
+			return ctx, fmt.Errorf("couldn't open -maps file: %v", err)
+		}
+		defer f.Close()
+		if ctx.Maps, err = analyzer.ParseMapCatalog(f); err != nil {
+			return ctx, fmt.Errorf("invalid -maps file %v: %v", fMaps, err)
+		}
+	}
+	if fMapNameRules != "" {
+		f, err := os.Open(fMapNameRules)
+		if err != nil {
+			return ctx, fmt.Errorf("couldn't open -map-name-rules file: %v", err)
+		}
+		defer f.Close()
+		if ctx.MapNameRules, err = analyzer.ParseMapNameRules(f); err != nil {
+			return ctx, fmt.Errorf("invalid -map-name-rules file %v: %v", fMapNameRules, err)
+		}
+	}
+	return ctx, nil
 }
 
 func resolveAnalyzerRequests(stringFlags map[string]*string, boolFlags map[string]*bool) [][]string {
@@ -127,6 +218,21 @@ func resolveAnalyzerRequests(stringFlags map[string]*string, boolFlags map[strin
 	return analyzerRequests
 }
 
+// appendMissingAnalyzerRequests appends the given analyzer requests that were not already requested.
+func appendMissingAnalyzerRequests(analyzerRequests [][]string, toAppend [][]string) [][]string {
+	requested := map[string]struct{}{}
+	for _, analyzerRequest := range analyzerRequests {
+		requested[strings.Join(analyzerRequest, ",")] = struct{}{}
+	}
+	for _, analyzerRequest := range toAppend {
+		if _, ok := requested[strings.Join(analyzerRequest, ",")]; !ok {
+			requested[strings.Join(analyzerRequest, ",")] = struct{}{}
+			analyzerRequests = append(analyzerRequests, analyzerRequest)
+		}
+	}
+	return analyzerRequests
+}
+
 func resolveReplayPaths(fs *flag.FlagSet) []string {
 	var fReplay, fReplays, fReplayDir string
 	if fs.Lookup("replay") != nil {
@@ -1,8 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/marianogappa/sctool/analyzer"
 )
 
 func TestAnalyzers(t *testing.T) {
@@ -29,6 +39,289 @@ func TestAnalyzers(t *testing.T) {
 			},
 			expected: [][]string{{"true", "false", "true", "Transistor1.2", "PvZ", "true", "ZvP", "adultrabbit", "Zerg", "true"}},
 		},
+		{
+			name: "tests analyzers with dependencies",
+			args: []string{
+				"-duration-minutes-is-greater-than", "20",
+				"-matchup-is", "zvp",
+				"-my-matchup-is", "PvZ",
+				"-my-win",
+				"-filter--my-race-is", "zerg",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"true", "true", "true", "false", ""}},
+		},
+		{
+			name: "tests -where matching",
+			args: []string{
+				"-my-matchup",
+				"-where", "my-matchup-is(TvZ) or (my-matchup-is(ZvP) and not duration-minutes-is-lower-than(10)) or is-2v2",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"ZvP"}},
+		},
+		{
+			name: "tests -where not matching",
+			args: []string{
+				"-my-matchup",
+				"-where", "my-matchup-is(ZvP) AND NOT (is-2v2 or my-race-is(Zerg))",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{},
+		},
+		{
+			name: "tests numeric comparison filters",
+			args: []string{
+				"-my-first-specific-unit-seconds", "Spawning Pool",
+				"-filter--my-apm", ">=300",
+				"-filter-not--my-first-specific-unit-seconds", "Hatchery, < 60",
+				"-where", "duration-minutes(>20) and my-first-specific-unit-seconds(Spawning Pool, <=1:30)",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"373", "114", "90"}},
+		},
+		{
+			name: "tests numeric comparison filters not matching",
+			args: []string{
+				"-my-apm",
+				"-filter--my-first-specific-unit-seconds", "Spawning Pool,<90",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{},
+		},
+		{
+			name: "tests opponent analyzers",
+			args: []string{
+				"-opponent-apm",
+				"-opponent-first-specific-unit-seconds", "Gateway",
+				"-opponent-name",
+				"-opponent-name-is", "somebody,Moo.Sapa",
+				"-opponent-names",
+				"-opponent-race",
+				"-opponent-race-is", "Protoss",
+				"-filter-not--opponent-name-is", "adultrabbit",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"false", "321", "150", "Moo.Sapa", "true", "Moo.Sapa", "Protoss", "true"}},
+		},
+		{
+			name: "tests -my-build-order",
+			args: []string{
+				"-my-build-order", "10",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"0:00 Drone,0:14 Drone,0:23 Drone,0:33 Drone,0:40 Drone,0:53 Overlord,1:20 Drone,1:27 Drone,1:30 Spawning Pool,1:54 Hatchery"}},
+		},
+		{
+			name: "tests -per-player",
+			args: []string{
+				"-my-apm",
+				"-my-name",
+				"-my-race",
+				"-my-start-location",
+				"-my-team",
+				"-my-win",
+				"-per-player",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{
+				{"321", "Moo.Sapa", "Protoss", "108,8", "0", ""},
+				{"373", "adultrabbit", "Zerg", "108,119", "0", ""},
+			},
+		},
+		{
+			name: "tests -per-player with filters",
+			args: []string{
+				"-my-name",
+				"-filter--my-first-specific-unit-seconds", "Spawning Pool,<120",
+				"-per-player",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"90", "adultrabbit"}},
+		},
+		{
+			name: "tests -my-opening",
+			args: []string{
+				"-my-name",
+				"-my-opening",
+				"-per-player",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"Moo.Sapa", "nexus first"}, {"adultrabbit", "overpool"}},
+		},
+		{
+			name: "tests -my-actions, -my-eapm and -my-redundancy",
+			args: []string{
+				"-my-actions",
+				"-my-apm",
+				"-my-eapm",
+				"-my-redundancy",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"10652 6197 217 0.42 584,512,417,432,437,425,447,458,401,341,396,326,313,333,321,331,395,373,340,344,330,324,353,258,318,286,300,334,223 166,100,207,229,154,212,214,233,237,236,218,191,217,234,221,220,257,246,217,239,240,232,259,192,226,207,243,212,138", "373", "217", "0.42"}},
+		},
+		{
+			name: "tests APM over time",
+			args: []string{
+				"-my-apm-at-minute", "10",
+				"-my-apm-per-minute",
+				"-my-peak-apm",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"396", "584,512,417,432,437,425,447,458,401,341,396,326,313,333,321,331,395,373,340,344,330,324,353,258,318,286,300,334,223", "584"}},
+		},
+		{
+			name: "tests expansions",
+			args: []string{
+				"-my-expanded-before-first-gas",
+				"-my-expansion-seconds", "1",
+				"-my-expansions",
+				"-my-town-halls",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"true", "114", "6", "13"}},
+		},
+		{
+			name: "tests proxy analyzers",
+			args: []string{
+				"-my-proxy", "30",
+				"-opponent-proxy", "10",
+				"-filter-not--proxy-detected", "30",
+				"-where", "not my-proxy",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"false", "false", "false"}},
+		},
+		{
+			name: "tests unit count analyzers",
+			args: []string{
+				"-my-unit-composition",
+				"-my-unit-count", "Drone,5",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"Zergling 344,Drone 127,Hydralisk 77,Scourge 38,Overlord 34,Ultralisk 12,Lurker 11,Defiler 3", "26"}},
+		},
+		{
+			name: "tests worker analyzers",
+			args: []string{
+				"-my-longest-worker-gap-seconds", "10",
+				"-my-worker-count-at-minute", "5",
+				"-my-workers-per-minute",
+				"-my-workers-produced-per-minute",
+				"-per-player",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{
+				{"131", "36", "10,5,8,10,9,9,9,9,0,0,0,0,5,0,4,2,0,0,0,0,0,1,0,0,2", "4,5,5,9,9,10,10,9,8,0,0,0,5,0,2,4,0,0,0,0,0,1,0,0,2"},
+				{"117", "25", "5,2,5,4,10,7,0,4,1,10,5,15,10,7,0,1,1,5,14,0,2,12,0,4,0,1,2", "5,2,4,5,5,12,0,4,1,6,9,14,11,7,0,1,1,5,14,0,2,12,0,4,0,1,2"},
+			},
+		},
+		{
+			name: "tests spawn analyzers",
+			args: []string{
+				"-my-start-clock",
+				"-opponent-start-clock",
+				"-spawn-distance",
+				"-spawn-type",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"5", "1", "111", "vertical"}},
+		},
+		{
+			name: "tests map fingerprint",
+			args: []string{
+				"-map-fingerprint",
+				"-canonical-map",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"", "c30a33090698d7fe"}},
+		},
+		{
+			name: "tests map name analyzers",
+			args: []string{
+				"-map-name-normalized",
+				"-map-name-is", "Fighting Spirit,| iCCup | transistor 1.3",
+				"-filter--map-name-matches", "^trans.{1,3}tor$",
+				"-where", "not map-name-matches(spirit)",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"true", "true", "Transistor"}},
+		},
+		{
+			name: "tests chat analyzers",
+			args: []string{
+				"-chat",
+				"-chat-contains", "ㅎ",
+				"-chat-message-count",
+				"-said-gg",
+				"-filter-not--chat-contains", "gg",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"false", "28:33 adultrabbit ㅎㅎ", "true", "1", ""}},
+		},
+		{
+			name: "tests leave and winner analyzers",
+			args: []string{
+				"-leaves",
+				"-first-leaver",
+				"-my-leave-reason",
+				"-winners",
+				"-winner-confidence",
+				"-my-inferred-win",
+				"-per-player",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{
+				{"Moo.Sapa", "Moo.Sapa 28:33 40800 Defeat", "false", "Defeat", "leave-reason", "adultrabbit"},
+				{"Moo.Sapa", "Moo.Sapa 28:33 40800 Defeat", "true", "", "leave-reason", "adultrabbit"},
+			},
+		},
+		{
+			name: "tests team format analyzers",
+			args: []string{
+				"-team-format",
+				"-team-format-is", "2V2,1v1",
+				"-filter-not--team-format-is", "ffa4",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"false", "1v1", "true"}},
+		},
+		{
+			name: "tests matchup patterns",
+			args: []string{
+				"-matchup-is", "z v ?",
+				"-my-matchup",
+				"-my-matchup-is", "?vz",
+				"-filter--my-matchup-is", "Z vs ?",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"true", "true", "ZvP", "false"}},
+		},
+		{
+			name: "tests tech and upgrade analyzers",
+			args: []string{
+				"-my-first-tech-seconds", "lurker aspect",
+				"-my-first-upgrade-seconds", "METABOLIC BOOST",
+				"-my-upgrades",
+				"-me", "adultrabbit",
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			},
+			expected: [][]string{{"559", "217", "Metabolic Boost (Zergling Speed),Pneumatized Carapace (Overlord Speed),Muscular Augments (Hydralisk Speed),Zerg Missile Attacks,Grooved Spines (Hydralisk Range),Zerg Missile Attacks,Zerg Carapace,Zerg Melee Attacks,Adrenal Glands (Zergling Attack),Zerg Flyer Carapace,Zerg Carapace,Zerg Melee Attacks,Zerg Missile Attacks,Ventral Sacs (Overlord Transport),Defiler Energy,Zerg Carapace,Zerg Melee Attacks,Chitinous Plating (Ultralisk Armor),Antennae (Overlord Sight),Anabolic Synthesis (Ultralisk Speed)"}},
+		},
 	}
 	for _, tc := range ts {
 		t.Run(tc.name, func(t *testing.T) {
@@ -42,10 +335,514 @@ func TestAnalyzers(t *testing.T) {
 				t.Errorf("Expected no errors executing AnalyzerExecutor but: %v", errs)
 				t.FailNow()
 			}
-			if !reflect.DeepEqual(tc.expected, results) {
+			if !reflect.DeepEqual(tc.expected, resultsToStrings(results)) {
 				t.Errorf("Expected: %v, but got: %v", tc.expected, results)
 				t.FailNow()
 			}
 		})
 	}
 }
+
+func TestWorkersKeepReplayOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp dir but: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bs, err := ioutil.ReadFile("testdata/larvavsMini.rep")
+	if err != nil {
+		t.Fatalf("Expected no errors reading replay but: %v", err)
+	}
+	expected := [][]string{}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("replay%02d", i)
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".rep"), bs, 0644); err != nil {
+			t.Fatalf("Expected no errors writing replay but: %v", err)
+		}
+		expected = append(expected, []string{"ZvP", name})
+	}
+	for _, workers := range []string{"1", "4", "32"} {
+		t.Run("workers "+workers, func(t *testing.T) {
+			executor, _, errs := buildAnalyzerExecutor([]string{
+				"-replay-name", "-my-matchup", "-me", "adultrabbit",
+				"-replay-dir", dir, "-workers", workers, "-o", "none",
+			})
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+			}
+			results, errs := executor.ExecuteWithResults()
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+			}
+			if !reflect.DeepEqual(expected, resultsToStrings(results)) {
+				t.Fatalf("Expected: %v, but got: %v", expected, results)
+			}
+		})
+	}
+}
+
+func TestOpeningsFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp file but: %v", err)
+	}
+	defer os.Remove(f.Name())
+	openings := `[{"name": "pool first", "race": "zerg", "conditions": [{"item": "Spawning Pool", "before": "Hatchery"}]}]`
+	if _, err := f.WriteString(openings); err != nil {
+		t.Fatalf("Expected no errors writing temp file but: %v", err)
+	}
+	f.Close()
+	executor, _, errs := buildAnalyzerExecutor([]string{
+		"-my-name", "-my-opening", "-per-player", "-openings", f.Name(),
+		"-replay", "testdata/larvavsMini.rep", "-o", "none",
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	results, errs := executor.ExecuteWithResults()
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+	}
+	expected := [][]string{{"Moo.Sapa", ""}, {"adultrabbit", "pool first"}}
+	if !reflect.DeepEqual(expected, resultsToStrings(results)) {
+		t.Fatalf("Expected: %v, but got: %v", expected, results)
+	}
+}
+
+func TestParseOpenings(t *testing.T) {
+	defaultOpenings, err := json.Marshal(analyzer.DefaultOpenings)
+	if err != nil {
+		t.Fatalf("Expected no errors encoding DefaultOpenings but: %v", err)
+	}
+	ts := []struct {
+		name          string
+		openings      string
+		expectedError string
+	}{
+		{
+			name:     "DefaultOpenings are valid",
+			openings: string(defaultOpenings),
+		},
+		{
+			name:     "Before can be an item or a game time",
+			openings: `[{"name": "fast pool", "race": "zerg", "conditions": [{"item": "Drone", "before": "Spawning Pool", "max": 5}, {"item": "Spawning Pool", "before": "2:30"}]}]`,
+		},
+		{
+			name:          "Unknown item",
+			openings:      `[{"name": "pool first", "race": "zerg", "conditions": [{"item": "Spawning Pol"}]}]`,
+			expectedError: `opening pool first has a condition on an unknown item "Spawning Pol"; please use a building, unit, tech or upgrade name e.g. Spawning Pool`,
+		},
+		{
+			name:          "Unknown before",
+			openings:      `[{"name": "pool first", "race": "zerg", "conditions": [{"item": "Spawning Pool", "before": "Hatch"}]}]`,
+			expectedError: `opening pool first has a condition on Spawning Pool before an unknown item or time "Hatch"; please use a building, unit, tech or upgrade name e.g. Hatchery, or a game time e.g. 2:30`,
+		},
+	}
+	for _, ts := range ts {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := analyzer.ParseOpenings(strings.NewReader(ts.openings))
+			if ts.expectedError == "" && err != nil {
+				t.Fatalf("Expected no errors parsing openings but: %v", err)
+			}
+			if ts.expectedError != "" && (err == nil || err.Error() != ts.expectedError) {
+				t.Fatalf("Expected error: %v, but got: %v", ts.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestMapCatalog(t *testing.T) {
+	ts := []struct {
+		name     string
+		catalog  string
+		expected [][]string
+	}{
+		{
+			name:     "matches by name",
+			catalog:  `[{"name": "Transistor", "names": [" transistor1.2\u0003 "]}]`,
+			expected: [][]string{{"Transistor"}},
+		},
+		{
+			name:     "matches by fingerprint before name",
+			catalog:  `[{"name": "Other", "names": ["Transistor1.2"]}, {"name": "Transistor", "fingerprints": ["C30A33090698D7FE"]}]`,
+			expected: [][]string{{"Transistor"}},
+		},
+		{
+			name:     "is empty for unknown maps",
+			catalog:  `[{"name": "Fighting Spirit", "fingerprints": ["0123456789abcdef"]}]`,
+			expected: [][]string{{""}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "sctool")
+			if err != nil {
+				t.Fatalf("Expected no errors creating temp file but: %v", err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(tc.catalog); err != nil {
+				t.Fatalf("Expected no errors writing temp file but: %v", err)
+			}
+			f.Close()
+			executor, _, errs := buildAnalyzerExecutor([]string{
+				"-canonical-map", "-maps", f.Name(),
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			})
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+			}
+			results, errs := executor.ExecuteWithResults()
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+			}
+			if !reflect.DeepEqual(tc.expected, resultsToStrings(results)) {
+				t.Fatalf("Expected: %v, but got: %v", tc.expected, results)
+			}
+		})
+	}
+}
+
+func TestMapNameRulesFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp file but: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`["sistor.*$"]`); err != nil {
+		t.Fatalf("Expected no errors writing temp file but: %v", err)
+	}
+	f.Close()
+	executor, _, errs := buildAnalyzerExecutor([]string{
+		"-map-name-normalized", "-map-name-is", "Tran", "-map-name-rules", f.Name(),
+		"-replay", "testdata/larvavsMini.rep", "-o", "none",
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	results, errs := executor.ExecuteWithResults()
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+	}
+	expected := [][]string{{"true", "Tran"}}
+	if !reflect.DeepEqual(expected, resultsToStrings(results)) {
+		t.Fatalf("Expected: %v, but got: %v", expected, results)
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp dir but: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ts := []struct {
+		name     string
+		args     []string
+		expected [][]string
+	}{
+		{
+			name:     "populates the cache",
+			args:     []string{"-my-race", "-my-apm", "-me", "adultrabbit"},
+			expected: [][]string{{"373", "Zerg"}},
+		},
+		{
+			name:     "reads from the cache",
+			args:     []string{"-my-race", "-my-apm", "-me", "adultrabbit"},
+			expected: [][]string{{"373", "Zerg"}},
+		},
+		{
+			name:     "is keyed by context",
+			args:     []string{"-my-race", "-my-apm", "-me", "Moo.Sapa"},
+			expected: [][]string{{"321", "Protoss"}},
+		},
+		{
+			name:     "computes missing analyzers",
+			args:     []string{"-my-race", "-my-name", "-me", "adultrabbit"},
+			expected: [][]string{{"adultrabbit", "Zerg"}},
+		},
+		{
+			name:     "is keyed by player in per-player mode",
+			args:     []string{"-my-race", "-my-apm", "-per-player"},
+			expected: [][]string{{"321", "Protoss"}, {"373", "Zerg"}},
+		},
+		{
+			name:     "reads the players from the cache in per-player mode",
+			args:     []string{"-my-race", "-my-apm", "-per-player"},
+			expected: [][]string{{"321", "Protoss"}, {"373", "Zerg"}},
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append(tc.args, "-cache-dir", dir, "-replay", "testdata/larvavsMini.rep", "-o", "none")
+			executor, _, errs := buildAnalyzerExecutor(args)
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+			}
+			results, errs := executor.ExecuteWithResults()
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+			}
+			if !reflect.DeepEqual(tc.expected, resultsToStrings(results)) {
+				t.Fatalf("Expected: %v, but got: %v", tc.expected, results)
+			}
+			files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("Expected exactly one cache file but got: %v (%v)", files, err)
+			}
+		})
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected exactly one cache file but got: %v (%v)", files, err)
+	}
+	bs, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Expected no errors reading cache file but: %v", err)
+	}
+	cacheFile := struct {
+		PlayerIDs []int `json:"playerIds"`
+	}{}
+	if err := json.Unmarshal(bs, &cacheFile); err != nil {
+		t.Fatalf("Expected a valid cache file but: %v", err)
+	}
+	if expected := []int{1, 0}; !reflect.DeepEqual(expected, cacheFile.PlayerIDs) {
+		t.Fatalf("Expected the cache file to have player IDs %v so that per-player mode can skip parsing, but got: %v", expected, cacheFile.PlayerIDs)
+	}
+}
+
+func TestCacheDirIsKeyedByContents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp dir but: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bs, err := ioutil.ReadFile("testdata/larvavsMini.rep")
+	if err != nil {
+		t.Fatalf("Expected no errors reading replay but: %v", err)
+	}
+	moved := filepath.Join(dir, "moved.rep")
+	if err := ioutil.WriteFile(moved, bs, 0644); err != nil {
+		t.Fatalf("Expected no errors writing replay but: %v", err)
+	}
+	cacheDir := filepath.Join(dir, "cache")
+	ts := []struct {
+		name            string
+		args            []string
+		expected        [][]string
+		expectedEntries int
+	}{
+		{
+			name:            "populates the cache",
+			args:            []string{"-my-race", "-map-name", "-me", "adultrabbit", "-replay", "testdata/larvavsMini.rep"},
+			expected:        [][]string{{"Transistor1.2", "Zerg"}},
+			expectedEntries: 2,
+		},
+		{
+			name:            "reads from the cache for a moved replay",
+			args:            []string{"-my-race", "-map-name", "-me", "adultrabbit", "-replay", moved},
+			expected:        [][]string{{"Transistor1.2", "Zerg"}},
+			expectedEntries: 2,
+		},
+		{
+			name:            "populates the cache for path-dependent analyzers",
+			args:            []string{"-replay-name", "-replay-path", "-replay", "testdata/larvavsMini.rep"},
+			expected:        [][]string{{"larvavsMini", "testdata/larvavsMini.rep"}},
+			expectedEntries: 4,
+		},
+		{
+			name:            "is keyed by path for path-dependent analyzers",
+			args:            []string{"-replay-name", "-replay-path", "-replay", moved},
+			expected:        [][]string{{"moved", moved}},
+			expectedEntries: 6,
+		},
+	}
+	for _, tc := range ts {
+		t.Run(tc.name, func(t *testing.T) {
+			executor, _, errs := buildAnalyzerExecutor(append(tc.args, "-cache-dir", cacheDir, "-o", "none"))
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+			}
+			results, errs := executor.ExecuteWithResults()
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+			}
+			if !reflect.DeepEqual(tc.expected, resultsToStrings(results)) {
+				t.Fatalf("Expected: %v, but got: %v", tc.expected, results)
+			}
+			files, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("Expected exactly one cache file but got: %v (%v)", files, err)
+			}
+			bs, err := ioutil.ReadFile(files[0])
+			if err != nil {
+				t.Fatalf("Expected no errors reading cache file but: %v", err)
+			}
+			cacheFile := struct {
+				Results map[string]interface{} `json:"results"`
+			}{}
+			if err := json.Unmarshal(bs, &cacheFile); err != nil {
+				t.Fatalf("Expected a valid cache file but: %v", err)
+			}
+			if len(cacheFile.Results) != tc.expectedEntries {
+				t.Fatalf("Expected %v cache entries, but got: %v", tc.expectedEntries, cacheFile.Results)
+			}
+		})
+	}
+}
+
+func TestCacheDirIsKeyedByOpenings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sctool")
+	if err != nil {
+		t.Fatalf("Expected no errors creating temp dir but: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	openingsPath := filepath.Join(dir, "openings.json")
+	for _, tc := range []struct {
+		name     string
+		opening  string
+		expected [][]string
+	}{
+		{name: "populates the cache", opening: "pool first", expected: [][]string{{"pool first"}}},
+		{name: "recomputes after editing the openings file", opening: "OTHER", expected: [][]string{{"OTHER"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openings := fmt.Sprintf(`[{"name": %q, "race": "zerg", "conditions": [{"item": "Spawning Pool", "before": "Hatchery"}]}]`, tc.opening)
+			if err := ioutil.WriteFile(openingsPath, []byte(openings), 0644); err != nil {
+				t.Fatalf("Expected no errors writing openings file but: %v", err)
+			}
+			executor, _, errs := buildAnalyzerExecutor([]string{
+				"-my-opening", "-me", "adultrabbit", "-openings", openingsPath, "-cache-dir", filepath.Join(dir, "cache"),
+				"-replay", "testdata/larvavsMini.rep", "-o", "none",
+			})
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+			}
+			results, errs := executor.ExecuteWithResults()
+			if len(errs) != 0 {
+				t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+			}
+			if !reflect.DeepEqual(tc.expected, resultsToStrings(results)) {
+				t.Fatalf("Expected: %v, but got: %v", tc.expected, results)
+			}
+		})
+	}
+}
+
+func TestJSONOutputHasNativeTypes(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		ctx      = analyzer.NewContext(map[string]struct{}{"adultrabbit": struct{}{}})
+		requests = [][]string{{"map-name"}, {"duration-minutes"}, {"is-1v1"}, {"my-apm"}, {"my-race"}, {"my-win"}, {"my-build-order", "2"}}
+	)
+	executor, errs := analyzer.NewExecutor([]string{"testdata/larvavsMini.rep"}, requests, ctx, analyzer.NewJSONOutput(&buf), "", analyzer.WithWorkers(1))
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	if errs := executor.Execute(); len(errs) != 0 {
+		t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+	}
+	var actual []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatalf("Expected valid JSON output but: %v (%v)", err, buf.String())
+	}
+	expected := []map[string]interface{}{{
+		"duration-minutes": float64(28),
+		"is-1v1":           true,
+		"map-name":         "Transistor1.2",
+		"my-apm":           float64(373),
+		"my-build-order(2)": []interface{}{
+			map[string]interface{}{"time": float64(0), "item": "Drone"},
+			map[string]interface{}{"time": float64(14), "item": "Drone"},
+		},
+		"my-race": "Zerg",
+		"my-win":  nil,
+	}}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected: %v, but got: %v", expected, actual)
+	}
+}
+
+func TestCSVOutputKeepsNullPlaceholders(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		ctx      = analyzer.NewContext(map[string]struct{}{"Moo.Sapa": struct{}{}})
+		requests = [][]string{{"my-first-specific-unit-seconds", "Spawning Pool"}, {"my-name"}, {"my-win"}}
+	)
+	executor, errs := analyzer.NewExecutor([]string{"testdata/larvavsMini.rep"}, requests, ctx, analyzer.NewCSVOutput(&buf), "", analyzer.WithWorkers(1))
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	if errs := executor.Execute(); len(errs) != 0 {
+		t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+	}
+	expected := `my-first-specific-unit-seconds(Spawning Pool),my-name,my-win
+-1,Moo.Sapa,unknown
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected: %v, but got: %v", expected, buf.String())
+	}
+}
+
+func TestJSONOutputHasNullForNonFiniteFloats(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		bs, err := json.Marshal(analyzer.NewFloatResult(f))
+		if err != nil || string(bs) != "null" {
+			t.Fatalf("Expected %v to be marshalled as null but got: %s (%v)", f, bs, err)
+		}
+	}
+}
+
+func TestAggregateOutput(t *testing.T) {
+	var buf bytes.Buffer
+	output, err := analyzer.NewAggregateOutput(analyzer.NewCSVOutput(&buf), "my-race",
+		"count, avg(my-apm), rate(my-race-is(Zerg)), min(my-first-specific-unit-seconds(Spawning Pool)), sum(duration-minutes)")
+	if err != nil {
+		t.Fatalf("Expected no errors building AggregateOutput but: %v", err)
+	}
+	executor, errs := analyzer.NewExecutor([]string{"testdata/larvavsMini.rep"}, output.AnalyzerRequests(),
+		analyzer.NewContext(map[string]struct{}{}), output, "", analyzer.WithWorkers(1), analyzer.WithPerPlayer(true))
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	if errs := executor.Execute(); len(errs) != 0 {
+		t.Fatalf("Expected no errors executing AnalyzerExecutor but: %v", errs)
+	}
+	expected := `my-race,count,avg(my-apm),rate(my-race-is(Zerg)),min(my-first-specific-unit-seconds(Spawning Pool)),sum(duration-minutes)
+Protoss,1,321,0,,28
+Zerg,1,373,1,90,28
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected: %v, but got: %v", expected, buf.String())
+	}
+}
+
+func TestAggregateOutputWithoutRequestedAnalyzer(t *testing.T) {
+	var buf bytes.Buffer
+	output, err := analyzer.NewAggregateOutput(analyzer.NewCSVOutput(&buf), "my-race", "count")
+	if err != nil {
+		t.Fatalf("Expected no errors building AggregateOutput but: %v", err)
+	}
+	executor, errs := analyzer.NewExecutor([]string{"testdata/larvavsMini.rep"}, [][]string{{"my-apm"}},
+		analyzer.NewContext(map[string]struct{}{}), output, "", analyzer.WithWorkers(1), analyzer.WithPerPlayer(true))
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors building AnalyzerExecutor but: %v", errs)
+	}
+	errs = executor.Execute()
+	if len(errs) != 1 || errs[0].Error() != "analyzer my-race to group by was not requested" {
+		t.Fatalf("Expected only the error of the missing analyzer to group by but got: %v", errs)
+	}
+	if buf.String() != "" {
+		t.Fatalf("Expected no output but got: %v", buf.String())
+	}
+}
+
+func resultsToStrings(results [][]analyzer.Result) [][]string {
+	ss := make([][]string, len(results))
+	for i := range results {
+		ss[i] = make([]string, len(results[i]))
+		for j := range results[i] {
+			ss[i][j] = results[i][j].String()
+		}
+	}
+	return ss
+}